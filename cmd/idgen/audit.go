@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/adobaai/studio_common/snowflake"
+	"github.com/adobaai/studio_common/snowflake/audit"
+)
+
+// runAudit 扫描数据库中已有的ID，检查重复和不可能的值
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	var (
+		driver      = fs.String("driver", "mysql", "database driver")
+		dsn         = fs.String("dsn", "", "data source name, e.g. user:pass@tcp(127.0.0.1:3306)/studio")
+		table       = fs.String("table", "", "table to scan")
+		column      = fs.String("column", "id", "column of the IDs")
+		layout      = layoutFlag(fs)
+		machines    = fs.String("machine-ids", "", "comma separated machine ids deployed, out of range machine ids are only flagged when given")
+		datacenters = fs.String("datacenter-ids", "", "comma separated datacenter ids deployed, out of range datacenter ids are only flagged when given")
+		since       = fs.String("since", "", "earliest possible creation date (2006-01-02), defaults to the epoch")
+		samples     = fs.Int("samples", 20, "number of IDs listed per problem")
+	)
+	_ = fs.Parse(args)
+
+	l, err := snowflake.ParseLayout(*layout)
+	if err != nil {
+		return err
+	}
+	op := &audit.Options{
+		Table:  *table,
+		Column: *column,
+		Config: audit.Config{Layout: l, Samples: *samples},
+	}
+	if op.MachineIDs, err = parseIDs(*machines); err != nil {
+		return err
+	}
+	if op.DatacenterIDs, err = parseIDs(*datacenters); err != nil {
+		return err
+	}
+	if *since != "" {
+		if op.Since, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			return err
+		}
+	}
+	if op.DB, err = sqlx.Open(*driver, *dsn); err != nil {
+		return err
+	}
+	defer op.DB.Close()
+
+	r, err := audit.Scan(context.Background(), op)
+	if err != nil {
+		return err
+	}
+	printReport(r)
+	if !r.OK() {
+		invalid := 0
+		for _, n := range r.InvalidByCause {
+			invalid += n
+		}
+		return fmt.Errorf("%d duplicated and %d invalid ids", r.DuplicateIDs, invalid)
+	}
+	return nil
+}
+
+func parseIDs(s string) (ids []int64, err error) {
+	if s == "" {
+		return nil, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(f), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", f)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func printReport(r *audit.Report) {
+	fmt.Printf("scanned %d ids, %d duplicated\n", r.Total, r.DuplicateIDs)
+	for _, d := range r.Duplicates {
+		fmt.Printf("  duplicate %d x%d\n", d.ID, d.Count)
+	}
+	if r.Unordered > 0 {
+		fmt.Printf("%d ids out of order, duplicates may be missed\n", r.Unordered)
+	}
+
+	reasons := make([]string, 0, len(r.InvalidByCause))
+	for reason := range r.InvalidByCause {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("invalid %s: %d\n", reason, r.InvalidByCause[audit.Reason(reason)])
+	}
+	for _, v := range r.Invalid {
+		if v.Reason == audit.ReasonMalformed {
+			fmt.Printf("  %s %q\n", v.Reason, v.Value)
+			continue
+		}
+		fmt.Printf("  %s %d\n", v.Reason, v.ID)
+	}
+
+	fmt.Println("ids per machine:")
+	machines := make([]int64, 0, len(r.Machines))
+	for m := range r.Machines {
+		machines = append(machines, m)
+	}
+	sort.Slice(machines, func(i, j int) bool { return machines[i] < machines[j] })
+	for _, m := range machines {
+		fmt.Printf("  %4d %d\n", m, r.Machines[m])
+	}
+
+	if len(r.Days) > 0 {
+		fmt.Println("ids per day:")
+		days := make([]string, 0, len(r.Days))
+		for d := range r.Days {
+			days = append(days, d)
+		}
+		sort.Strings(days)
+		for _, d := range days {
+			fmt.Printf("  %s %d\n", d, r.Days[d])
+		}
+	}
+}
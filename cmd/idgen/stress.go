@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+// runStress 模拟多台机器同时生成ID，检查是否有重复
+func runStress(args []string) error {
+	fs := flag.NewFlagSet("stress", flag.ExitOnError)
+	var (
+		layout  = layoutFlag(fs)
+		n       = fs.Int("n", 1000000, "number of IDs per worker")
+		workers = fs.Int("workers", 2, "number of workers, each with its own machine id")
+		atomic  = fs.Bool("atomic", false, "use the lock-free AtomicID")
+		out     = fs.String("out", "", "write the IDs to the file")
+	)
+	_ = fs.Parse(args)
+
+	l, err := snowflake.ParseLayout(*layout)
+	if err != nil {
+		return err
+	}
+	if int64(*workers) > l.MaxMachineID() {
+		return fmt.Errorf("layout %s supports at most %d workers", *layout, l.MaxMachineID())
+	}
+
+	var (
+		ch    = make(chan uint64, 1024)
+		wg    sync.WaitGroup
+		start = time.Now()
+		errs  = make(chan error, *workers)
+	)
+	for w := 0; w < *workers; w++ {
+		op := &snowflake.Options{MachineID: int64(w) + 1, Layout: l}
+		var next func() (uint64, error)
+		if *atomic {
+			id, err := snowflake.NewAtomic(op)
+			if err != nil {
+				return err
+			}
+			next = id.NextID
+		} else {
+			id, err := snowflake.New(op)
+			if err != nil {
+				return err
+			}
+			next = id.NextID
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < *n; i++ {
+				v, err := next()
+				if err != nil {
+					errs <- err
+					return
+				}
+				ch <- v
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	var w *bufio.Writer
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = bufio.NewWriter(f)
+		defer w.Flush()
+	}
+
+	seen := make(map[uint64]struct{}, *n**workers)
+	duplicates := 0
+	for v := range ch {
+		if _, exist := seen[v]; exist {
+			duplicates++
+		}
+		seen[v] = struct{}{}
+		if w != nil {
+			fmt.Fprintln(w, v)
+		}
+	}
+	elapsed := time.Since(start)
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
+	total := *n * *workers
+	fmt.Printf("generated %d ids in %s (%.0f ids/s), %d duplicates\n",
+		total, elapsed, float64(total)/elapsed.Seconds(), duplicates)
+	if duplicates > 0 {
+		return fmt.Errorf("%d duplicated ids", duplicates)
+	}
+	return nil
+}
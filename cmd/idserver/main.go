@@ -0,0 +1,121 @@
+// Command idserver serves snowflake IDs over HTTP/JSON for non-Go services.
+//
+//	idserver -addr :8080 -machine-id 1 -layout snowflake
+//	idserver -addr :8080 -redis 127.0.0.1:6379 -layout snowflake
+//	idserver -addr :8080 -machine-id-source hostname -layout snowflake
+//	idserver -addr :8080 -datacenter-id 1 -machine-id 1 -layout snowflake-dc
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	rds "github.com/go-redis/redis/v8"
+
+	"github.com/adobaai/studio_common/snowflake"
+	"github.com/adobaai/studio_common/snowflake/redislease"
+	"github.com/adobaai/studio_common/snowflake/server"
+)
+
+func main() {
+	var (
+		addr      = flag.String("addr", ":8080", "listen address")
+		dc        = flag.Int64("datacenter-id", 0, "datacenter id of the generator, for layouts with datacenter bits")
+		machineID = flag.Int64("machine-id", 0, "machine id of the generator, from 1")
+		source    = flag.String("machine-id-source", "", "take the machine id from env:NAME, hostname or ip:BITS instead of -machine-id")
+		layout    = flag.String("layout", "snowflake", "layout of the IDs: uid, uid32, snowflake or snowflake-dc")
+		redisAddr = flag.String("redis", "", "lease the machine id from redis instead of -machine-id")
+	)
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *addr, *dc, *machineID, *source, *layout, *redisAddr); err != nil {
+		log.Printf("idserver: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, addr string, dc, mid int64, source, layout, redisAddr string) error {
+	l, err := snowflake.ParseLayout(layout)
+	if err != nil {
+		return err
+	}
+
+	op := &snowflake.Options{DatacenterID: dc, MachineID: mid, Layout: l}
+	if source != "" {
+		if op.MachineIDSource, err = snowflake.ParseMachineIDSource(source); err != nil {
+			return err
+		}
+	}
+
+	r := snowflake.NewRegistry()
+	srv := server.New(r)
+	var lost <-chan struct{} // 使用Redis租约时，租约丢失后关闭
+
+	if redisAddr != "" {
+		// 每个数据中心使用各自的机器ID池
+		lease, err := redislease.Acquire(ctx, &redislease.LeaseOptions{
+			Rds:    rds.NewClient(&rds.Options{Addr: redisAddr}),
+			Prefix: leasePrefix(l, dc),
+			MaxID:  l.MaxMachineID(),
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := lease.Release(context.Background()); err != nil {
+				log.Printf("idserver: release machine id: %v", err)
+			}
+		}()
+		mid, lost = lease.MachineID(), lease.Lost()
+		op.MachineID, op.MachineIDSource = mid, nil
+
+		// 租约丢失后不能再使用该机器ID，立即停止发号，再关闭服务
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-lease.Lost():
+				srv.Stop()
+				log.Printf("idserver: machine id %d lost, shutting down", mid)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	if _, err = r.Register(server.DefaultName, op); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("idserver: listening on %s with datacenter id %d, machine id %d", ln.Addr(), dc, op.MachineID)
+	err = srv.Serve(ctx, ln)
+	select {
+	case <-lost:
+		// 租约丢失导致的关闭以非0状态退出，便于重新调度
+		return redislease.ErrLeaseLost
+	default:
+		return err
+	}
+}
+
+// leasePrefix 返回数据中心的机器ID池前缀，没有数据中心时使用默认前缀
+func leasePrefix(l snowflake.Layout, dc int64) string {
+	if l.DatacenterBits == 0 {
+		return ""
+	}
+	return fmt.Sprintf("studio.snowflake.machine.dc%d", dc)
+}
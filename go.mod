@@ -3,8 +3,10 @@ module github.com/adobaai/studio_common
 go 1.19
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.5
 	github.com/casbin/casbin/v2 v2.65.2
 	github.com/go-redis/redis/v8 v8.11.5
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.3.0
 	github.com/huandu/go-sqlbuilder v1.20.0
 	github.com/jmoiron/sqlx v1.3.5
@@ -13,11 +15,13 @@ require (
 
 require (
 	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/huandu/xstrings v1.3.2 // indirect
 	github.com/onsi/gomega v1.27.4 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v1.1.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
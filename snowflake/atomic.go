@@ -0,0 +1,127 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// AtomicID is a lock-free generator for hot paths: the last timestamp and
+// sequence are packed into one word and updated with compare-and-swap.
+// It supports the same options as ID except Checkpoint.
+type AtomicID struct {
+	layout    Layout
+	node      int64 // 拼接了数据中心id的机器id
+	rollback  RollbackPolicy
+	tolerance time.Duration
+	clock     Clock
+	observer  Observer
+	warnAt    atomic.Int64  // 到达该时间戳时通知observer即将到期
+	state     atomic.Uint64 // timestamp<<SequenceBits | sequence
+}
+
+func NewAtomic(op *Options) (*AtomicID, error) {
+	if err := initConfig(op); err != nil {
+		return nil, err
+	}
+	if op.Checkpoint != nil {
+		return nil, errors.New("snowflake: AtomicID does not support checkpoints")
+	}
+	id := &AtomicID{
+		layout:    op.Layout,
+		node:      op.Layout.node(op.DatacenterID, op.MachineID),
+		rollback:  op.Rollback,
+		tolerance: op.RollbackTolerance,
+		clock:     op.Clock,
+		observer:  op.Observer,
+	}
+	id.warnAt.Store(warnAt(op.Layout, op.ExpiryWarning))
+	return id, nil
+}
+
+// Layout returns the layout of the generated IDs.
+func (id *AtomicID) Layout() Layout {
+	return id.layout
+}
+
+// Decompose splits v, an ID generated with the same layout, into its parts.
+func (id *AtomicID) Decompose(v uint64) (Parts, error) {
+	return id.layout.decompose(v, id.clock.Now())
+}
+
+// NextID returns the next ID of the generator's layout.
+func (id *AtomicID) NextID() (uint64, error) {
+	return id.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID but gives up with a WaitError once ctx is done
+// while waiting for the clock.
+func (id *AtomicID) NextIDContext(ctx context.Context) (uint64, error) {
+	var (
+		l      = id.layout
+		maxSeq = uint64(l.MaxSequence())
+	)
+	for {
+		old := id.state.Load()
+		last, seq := int64(old>>l.SequenceBits), old&maxSeq
+		now := l.timestamp(id.clock.Now())
+
+		ts := now
+		if now < last {
+			behind := time.Duration(last-now) * l.TimeUnit
+			id.observer.ClockRollback(behind)
+			cause := fmt.Errorf("%w by %s", ErrClockMovedBackwards, behind)
+			if id.rollback == RollbackError || (id.tolerance >= 0 && behind > id.tolerance) {
+				return 0, cause
+			}
+			if id.rollback == RollbackWait {
+				if err := sleepUntil(ctx, id.clock, l, last, cause); err != nil {
+					return 0, err
+				}
+				continue
+			}
+		}
+		switch {
+		case now > last:
+			seq = 0
+		case seq < maxSeq:
+			ts, seq = last, seq+1
+		case now < last:
+			// 借用时间戳时序列号用尽，继续向后借用
+			id.observer.SequenceExhausted(0)
+			ts, seq = last+1, 0
+		default:
+			// 序列号用尽，等待下一个时间单位
+			start := id.clock.Now()
+			if err := sleepUntil(ctx, id.clock, l, last+1, ErrSequenceExhausted); err != nil {
+				return 0, err
+			}
+			id.observer.SequenceExhausted(id.clock.Now().Sub(start))
+			continue
+		}
+
+		if id.state.CompareAndSwap(old, uint64(ts)<<l.SequenceBits|seq) {
+			v, err := l.compose(ts, id.node, int64(seq))
+			if err == nil {
+				id.observer.IDIssued(1)
+			}
+			if w := id.warnAt.Load(); ts >= w && id.warnAt.CompareAndSwap(w, math.MaxInt64) {
+				id.observer.LayoutExpiring(l.expiry())
+			}
+			return v, err
+		}
+	}
+}
+
+// Generate returns NextID in decimal.
+func (id *AtomicID) Generate() (string, error) {
+	v, err := id.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(v, 10), nil
+}
@@ -0,0 +1,94 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicID_NextID(t *testing.T) {
+	id, err := NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	assert.NoError(t, err)
+
+	var (
+		mu  sync.Mutex
+		ids = make(map[uint64]struct{})
+		wg  sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]uint64, 0, HundredK)
+			var last uint64
+			for i := 0; i < HundredK; i++ {
+				v, err := id.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if v <= last {
+					t.Errorf("id %d is not greater than %d", v, last)
+					return
+				}
+				last = v
+				local = append(local, v)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range local {
+				if _, exist := ids[v]; exist {
+					t.Errorf("generated the repeated id: %d", v)
+					return
+				}
+				ids[v] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, ids, 8*HundredK)
+}
+
+func TestAtomicID_Rollback(t *testing.T) {
+	id, _ := NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout, Rollback: RollbackError})
+	ahead := uint64(SnowflakeLayout.timestamp(time.Now().Add(time.Second)))
+	id.state.Store(ahead << SnowflakeLayout.SequenceBits)
+	_, err := id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+
+	id, _ = NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout, Rollback: RollbackBorrow, RollbackTolerance: time.Minute})
+	id.state.Store(ahead<<SnowflakeLayout.SequenceBits | uint64(SnowflakeLayout.MaxSequence()))
+	_, err = id.NextID()
+	assert.NoError(t, err)
+	// 序列号用尽后向后借用时间戳
+	assert.Equal(t, (ahead+1)<<SnowflakeLayout.SequenceBits, id.state.Load())
+
+	_, err = NewAtomic(&Options{MachineID: 1, Checkpoint: &memCheckpoint{}})
+	assert.Error(t, err)
+}
+
+func benchmarkGenerator(b *testing.B, next func() (uint64, error)) {
+	b.SetParallelism(64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := next(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkID_NextID(b *testing.B) {
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	benchmarkGenerator(b, id.NextID)
+}
+
+func BenchmarkAtomicID_NextID(b *testing.B) {
+	id, _ := NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	benchmarkGenerator(b, id.NextID)
+}
@@ -0,0 +1,253 @@
+package audit
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+// Reason tells why an ID cannot have been generated.
+type Reason string
+
+const (
+	ReasonOverflow     Reason = "overflow"      // 超出Layout的位数
+	ReasonFuture       Reason = "future"        // 时间戳在未来
+	ReasonTooEarly     Reason = "too_early"     // 早于Config.Since，默认为Layout的起始时间
+	ReasonMachineID    Reason = "machine_id"    // 不在Config.MachineIDs中
+	ReasonDatacenterID Reason = "datacenter_id" // 不在Config.DatacenterIDs中
+	ReasonMalformed    Reason = "malformed"     // 负数或不是数字，无法解析为ID
+)
+
+// DefaultSamples is the number of IDs kept per problem when Config.Samples is zero.
+const DefaultSamples = 100
+
+// Config describes which IDs are possible.
+type Config struct {
+	Layout snowflake.Layout
+	Now    time.Time // 判断未来时间戳的时间，默认为当前时间
+	Since  time.Time // 最早可能的生成时间，默认为Layout.Epoch
+
+	// MachineIDs and DatacenterIDs are the ids deployed, empty to allow all.
+	MachineIDs    []int64
+	DatacenterIDs []int64
+
+	Location *time.Location // 按天统计使用的时区，默认为time.Local
+	Samples  int            // 每类问题保留的ID数量，默认为DefaultSamples
+}
+
+func initConfig(c *Config) error {
+	if err := c.Layout.Validate(); err != nil {
+		return err
+	}
+	if c.Now.IsZero() {
+		c.Now = time.Now()
+	}
+	if c.Since.IsZero() {
+		c.Since = c.Layout.Epoch
+	}
+	if c.Location == nil {
+		c.Location = time.Local
+	}
+	if c.Samples <= 0 {
+		c.Samples = DefaultSamples
+	}
+	return nil
+}
+
+// Duplicate is an ID found Count times.
+type Duplicate struct {
+	ID    uint64
+	Count int
+}
+
+// Invalid is an ID which cannot have been generated with the config.
+type Invalid struct {
+	ID     uint64
+	Value  string // 原始值，仅ReasonMalformed时有效
+	Reason Reason
+}
+
+// Report is the result of an audit. Duplicates keeps the Config.Samples IDs
+// repeated the most and Invalid the first Config.Samples IDs, the counts
+// cover all of them.
+type Report struct {
+	Total          int
+	DuplicateIDs   int // 出现多次的ID数量
+	Duplicates     []Duplicate
+	InvalidByCause map[Reason]int
+	Invalid        []Invalid
+	// Unordered counts the IDs added below the previous one, the duplicates
+	// may be incomplete if it is not zero.
+	Unordered int
+
+	// Machines counts the valid IDs per machine id, with the datacenter id in
+	// the high bits for layouts with DatacenterBits.
+	Machines map[int64]int
+	// Days counts the valid IDs per day of creation, empty for truncating
+	// layouts whose time is ambiguous.
+	Days map[string]int
+}
+
+// OK reports whether the audit found neither duplicate nor invalid ID.
+func (r *Report) OK() bool {
+	return r.DuplicateIDs == 0 && len(r.InvalidByCause) == 0 && r.Unordered == 0
+}
+
+// Auditor checks IDs one by one in ascending order, e.g. as read with
+// ORDER BY. It finds the duplicates by comparing each ID with the previous
+// one, so its memory does not grow with the number of IDs.
+type Auditor struct {
+	config      *Config
+	machines    map[int64]bool
+	datacenters map[int64]bool
+	last        uint64 // 上一个ID
+	count       int    // 上一个ID出现的次数
+	report      Report
+}
+
+func NewAuditor(c *Config) (*Auditor, error) {
+	if err := initConfig(c); err != nil {
+		return nil, err
+	}
+	a := &Auditor{
+		config: c,
+		report: Report{
+			InvalidByCause: make(map[Reason]int),
+			Machines:       make(map[int64]int),
+			Days:           make(map[string]int),
+		},
+	}
+	a.machines = set(c.MachineIDs)
+	a.datacenters = set(c.DatacenterIDs)
+	return a, nil
+}
+
+func set(ids []int64) map[int64]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	m := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m
+}
+
+// Add checks id, which must not be below the previous one.
+func (a *Auditor) Add(id uint64) {
+	r := &a.report
+	r.Total++
+	switch {
+	case a.count > 0 && id == a.last:
+		if a.count++; a.count == 2 {
+			r.DuplicateIDs++
+		}
+	default:
+		if a.count > 0 && id < a.last {
+			r.Unordered++
+		}
+		a.duplicate(r)
+		a.last, a.count = id, 1
+	}
+
+	l := a.config.Layout
+	p, err := l.DecomposeAt(id, a.config.Now)
+	// 不可能的ID不计入每台机器和每天的数量
+	switch {
+	case l.Bits() < 64 && id>>l.Bits() != 0:
+		a.invalid(id, ReasonOverflow)
+		return
+	case errors.Is(err, snowflake.ErrInvalidID):
+		a.invalid(id, ReasonFuture)
+		return
+	case !l.Truncate && p.Time.Before(a.config.Since):
+		a.invalid(id, ReasonTooEarly)
+		return
+	case a.datacenters != nil && !a.datacenters[p.DatacenterID]:
+		a.invalid(id, ReasonDatacenterID)
+		return
+	case a.machines != nil && !a.machines[p.MachineID]:
+		a.invalid(id, ReasonMachineID)
+		return
+	}
+
+	r.Machines[p.DatacenterID<<l.NodeBits|p.MachineID]++
+	if !l.Truncate {
+		r.Days[p.Time.In(a.config.Location).Format("2006-01-02")]++
+	}
+}
+
+// AddValue checks v, an ID in decimal as stored in the database. Negative
+// and non-numeric values are reported as ReasonMalformed.
+func (a *Auditor) AddValue(v string) {
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		a.report.Total++
+		a.record(Invalid{Value: v, Reason: ReasonMalformed})
+		return
+	}
+	a.Add(id)
+}
+
+// duplicate 上一个ID重复时记入r，只保留重复次数最多的Config.Samples个
+func (a *Auditor) duplicate(r *Report) {
+	if a.count < 2 {
+		return
+	}
+	d := Duplicate{ID: a.last, Count: a.count}
+	if len(r.Duplicates) < a.config.Samples {
+		r.Duplicates = append(r.Duplicates, d)
+		return
+	}
+	least := 0
+	for i, v := range r.Duplicates {
+		if v.Count < r.Duplicates[least].Count {
+			least = i
+		}
+	}
+	if d.Count > r.Duplicates[least].Count {
+		r.Duplicates[least] = d
+	}
+}
+
+func (a *Auditor) invalid(id uint64, reason Reason) {
+	a.record(Invalid{ID: id, Reason: reason})
+}
+
+func (a *Auditor) record(v Invalid) {
+	r := &a.report
+	r.InvalidByCause[v.Reason]++
+	if len(r.Invalid) < a.config.Samples {
+		r.Invalid = append(r.Invalid, v)
+	}
+}
+
+// Report returns the report of the IDs added so far.
+func (a *Auditor) Report() *Report {
+	r := a.report
+	r.InvalidByCause = copyMap(r.InvalidByCause)
+	r.Invalid = append([]Invalid(nil), r.Invalid...)
+	r.Machines = copyMap(r.Machines)
+	r.Days = copyMap(r.Days)
+	r.Duplicates = append([]Duplicate(nil), r.Duplicates...)
+	a.duplicate(&r)
+	// 重复次数多的在前
+	sort.Slice(r.Duplicates, func(i, j int) bool {
+		if r.Duplicates[i].Count != r.Duplicates[j].Count {
+			return r.Duplicates[i].Count > r.Duplicates[j].Count
+		}
+		return r.Duplicates[i].ID < r.Duplicates[j].ID
+	})
+	return &r
+}
+
+func copyMap[K comparable](m map[K]int) map[K]int {
+	c := make(map[K]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
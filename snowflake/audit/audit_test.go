@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func generate(t *testing.T, op *snowflake.Options, clock *snowflake.FakeClock, n int) []uint64 {
+	op.Clock = clock
+	id, err := snowflake.New(op)
+	assert.NoError(t, err)
+	ids, err := id.NextN(n)
+	assert.NoError(t, err)
+	return ids
+}
+
+func TestAuditor(t *testing.T) {
+	l := snowflake.SnowflakeLayout
+	a, err := NewAuditor(&Config{
+		Layout:     l,
+		Now:        testNow,
+		Since:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		MachineIDs: []int64{1, 2},
+		Location:   time.UTC,
+		Samples:    2,
+	})
+	assert.NoError(t, err)
+
+	clock := snowflake.NewFakeClock(testNow.Add(-36 * time.Hour))
+	all := generate(t, &snowflake.Options{MachineID: 1, Layout: l}, clock, 10)
+	clock.Set(testNow.Add(-time.Hour))
+	ids := generate(t, &snowflake.Options{MachineID: 2, Layout: l}, clock, 5)
+	all = append(all, ids...)
+	// 重复的ID
+	all = append(all, ids[0], ids[0], ids[1])
+
+	// 不可能的ID
+	clock.Set(testNow.Add(time.Hour))
+	future := generate(t, &snowflake.Options{MachineID: 1, Layout: l}, clock, 1)[0]
+	clock.Set(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	early := generate(t, &snowflake.Options{MachineID: 1, Layout: l}, clock, 1)[0]
+	clock.Set(testNow)
+	machine := generate(t, &snowflake.Options{MachineID: 3, Layout: l}, clock, 1)[0]
+	all = append(all, future, early, machine, 1<<63)
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for _, v := range all {
+		a.Add(v)
+	}
+
+	r := a.Report()
+	assert.False(t, r.OK())
+	assert.Equal(t, 22, r.Total)
+	assert.Equal(t, 2, r.DuplicateIDs)
+	assert.Equal(t, []Duplicate{{ids[0], 3}, {ids[1], 2}}, r.Duplicates)
+	assert.Zero(t, r.Unordered)
+	assert.Equal(t, map[Reason]int{
+		ReasonFuture:    1,
+		ReasonTooEarly:  1,
+		ReasonMachineID: 1,
+		ReasonOverflow:  1,
+	}, r.InvalidByCause)
+	assert.Equal(t, []Invalid{{ID: early, Reason: ReasonTooEarly}, {ID: machine, Reason: ReasonMachineID}}, r.Invalid)
+	// 不可能的ID不计入每台机器和每天的数量
+	assert.Equal(t, map[int64]int{1: 10, 2: 8}, r.Machines)
+	assert.Equal(t, map[string]int{"2024-05-31": 10, "2024-06-01": 8}, r.Days)
+}
+
+func TestAuditor_Duplicates(t *testing.T) {
+	a, err := NewAuditor(&Config{Layout: snowflake.SnowflakeLayout, Now: testNow, Samples: 2})
+	assert.NoError(t, err)
+	clock := snowflake.NewFakeClock(testNow)
+	ids := generate(t, &snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout}, clock, 4)
+	for i, v := range ids {
+		for n := 0; n <= i; n++ {
+			a.Add(v)
+		}
+	}
+
+	// 只保留重复次数最多的ID
+	r := a.Report()
+	assert.Equal(t, 3, r.DuplicateIDs)
+	assert.Equal(t, []Duplicate{{ids[3], 4}, {ids[2], 3}}, r.Duplicates)
+
+	// 乱序时无法保证找到所有重复的ID
+	a.Add(ids[0])
+	r = a.Report()
+	assert.Equal(t, 1, r.Unordered)
+	assert.False(t, r.OK())
+}
+
+func TestAuditor_Truncate(t *testing.T) {
+	// UIDLayout约32s循环一次，同一台机器也会生成重复的ID
+	a, err := NewAuditor(&Config{Layout: snowflake.UIDLayout, Now: testNow, DatacenterIDs: []int64{0}})
+	assert.NoError(t, err)
+	clock := snowflake.NewFakeClock(testNow.Add(-time.Minute))
+	id, _ := snowflake.New(&snowflake.Options{MachineID: 1, Clock: clock})
+	for i := 0; i < 2; i++ {
+		v, err := id.NextID()
+		assert.NoError(t, err)
+		a.Add(v)
+		clock.Add(snowflake.UIDLayout.Lifetime(testNow).Period)
+	}
+	a.Add(1 << 32)
+
+	r := a.Report()
+	assert.Equal(t, 1, r.DuplicateIDs)
+	assert.Equal(t, map[Reason]int{ReasonOverflow: 1}, r.InvalidByCause)
+	assert.Equal(t, map[int64]int{1: 2}, r.Machines)
+	assert.Empty(t, r.Days)
+}
+
+func TestAuditor_Datacenter(t *testing.T) {
+	l := snowflake.SnowflakeDCLayout
+	a, err := NewAuditor(&Config{Layout: l, Now: testNow, DatacenterIDs: []int64{1}})
+	assert.NoError(t, err)
+	clock := snowflake.NewFakeClock(testNow)
+	for dc := int64(1); dc <= 2; dc++ {
+		for _, v := range generate(t, &snowflake.Options{DatacenterID: dc, MachineID: 1, Layout: l}, clock, 3) {
+			a.Add(v)
+		}
+	}
+	r := a.Report()
+	assert.Equal(t, map[Reason]int{ReasonDatacenterID: 3}, r.InvalidByCause)
+	assert.Equal(t, map[int64]int{1<<5 | 1: 3}, r.Machines)
+}
+
+func TestAuditor_AddValue(t *testing.T) {
+	l := snowflake.SnowflakeLayout
+	a, err := NewAuditor(&Config{Layout: l, Now: testNow})
+	assert.NoError(t, err)
+	clock := snowflake.NewFakeClock(testNow)
+	ids := generate(t, &snowflake.Options{MachineID: 1, Layout: l}, clock, 2)
+	for _, v := range ids {
+		a.AddValue(strconv.FormatUint(v, 10))
+	}
+	// 有符号或字符串列中的异常值
+	a.AddValue("-1")
+	a.AddValue("abc")
+
+	r := a.Report()
+	assert.Equal(t, 4, r.Total)
+	assert.Equal(t, map[Reason]int{ReasonMalformed: 2}, r.InvalidByCause)
+	assert.Equal(t, []Invalid{{Value: "-1", Reason: ReasonMalformed}, {Value: "abc", Reason: ReasonMalformed}}, r.Invalid)
+	assert.Equal(t, map[int64]int{1: 2}, r.Machines)
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/jmoiron/sqlx"
+)
+
+type Options struct {
+	DB     *sqlx.DB
+	Table  string
+	Column string // 存储ID的列，如主键
+	Config
+}
+
+func initOptions(op *Options) error {
+	if op.DB == nil {
+		return errors.New("invalid db")
+	}
+	if op.Table == "" || op.Column == "" {
+		return errors.New("invalid table or column")
+	}
+	return nil
+}
+
+// Scan reads every ID of the column in ascending order and audits them as
+// they stream, the column should be indexed, e.g. the primary key. NULL
+// values are skipped, negative and non-numeric values are reported as
+// ReasonMalformed.
+func Scan(ctx context.Context, op *Options) (*Report, error) {
+	if err := initOptions(op); err != nil {
+		return nil, err
+	}
+	a, err := NewAuditor(&op.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, args := scanSQL(op).Build()
+	rows, err := op.DB.QueryxContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// 按原始值读取，有符号或字符串列中的异常值不会中断扫描
+	var v sql.RawBytes
+	for rows.Next() {
+		if err = rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		a.AddValue(string(v))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return a.Report(), nil
+}
+
+// scanSQL 按ID升序读取，重复的ID相邻，不需要在内存中保存所有ID
+func scanSQL(op *Options) *sqlbuilder.SelectBuilder {
+	sb := sqlbuilder.Select(op.Column).From(op.Table)
+	sb.Where(sb.IsNotNull(op.Column))
+	return sb.OrderBy(op.Column).Asc()
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+// fakeDriver 对任何查询都按顺序返回values，并记录最后一次查询
+type fakeDriver struct {
+	values []driver.Value
+	query  string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.query = query
+	return fakeStmt{c.d}, nil
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt struct{ d *fakeDriver }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{values: s.d.values}, nil
+}
+
+type fakeRows struct{ values []driver.Value }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestScan(t *testing.T) {
+	clock := snowflake.NewFakeClock(testNow)
+	ids := generate(t, &snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout}, clock, 2)
+	d := &fakeDriver{values: []driver.Value{
+		int64(-1), int64(ids[0]), []byte("abc"), int64(ids[1]), int64(ids[1]),
+	}}
+	sql.Register("audit_fake", d)
+
+	r, err := Scan(context.Background(), &Options{
+		DB:     sqlx.MustOpen("audit_fake", ""),
+		Table:  "users",
+		Column: "uid",
+		Config: Config{Layout: snowflake.SnowflakeLayout, Now: testNow},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT uid FROM users WHERE uid IS NOT NULL ORDER BY uid ASC", d.query)
+	assert.Equal(t, 5, r.Total)
+	assert.Equal(t, []Duplicate{{ids[1], 2}}, r.Duplicates)
+	assert.Equal(t, map[Reason]int{ReasonMalformed: 2}, r.InvalidByCause)
+	assert.Equal(t, map[int64]int{1: 3}, r.Machines)
+
+	_, err = Scan(context.Background(), &Options{DB: sqlx.MustOpen("audit_fake", "")})
+	assert.Error(t, err)
+}
+
+func TestScan_SQL(t *testing.T) {
+	sqlStr, args := scanSQL(&Options{Table: "users", Column: "uid"}).Build()
+	s, err := sqlbuilder.MySQL.Interpolate(sqlStr, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT uid FROM users WHERE uid IS NOT NULL ORDER BY uid ASC", s)
+}
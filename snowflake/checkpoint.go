@@ -0,0 +1,158 @@
+package snowflake
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultCheckpointInterval is used when Options.CheckpointInterval is zero.
+const DefaultCheckpointInterval = 3 * time.Second
+
+// CheckpointStore persists the high-water time of a generator, so that it
+// never issues IDs below it again after a restart.
+type CheckpointStore interface {
+	// Load returns the zero time if nothing has been saved yet.
+	Load() (time.Time, error)
+	Save(t time.Time) error
+}
+
+// checkpoint 保存下一个检查点，此前的时间戳可以直接使用
+func (id *ID) checkpoint(now int64) error {
+	if id.store == nil || now < id.reserved {
+		return nil
+	}
+	reserved := now + id.interval
+	if err := id.store.Save(id.layout.Epoch.Add(time.Duration(reserved) * id.layout.TimeUnit)); err != nil {
+		return err
+	}
+	id.reserved = reserved
+	return nil
+}
+
+// FileCheckpoint stores the checkpoint in a local file.
+type FileCheckpoint struct {
+	path string
+}
+
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path}
+}
+
+func (c *FileCheckpoint) Load() (t time.Time, err error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return
+	}
+	err = t.UnmarshalText(data)
+	return
+}
+
+// Save writes the checkpoint to a temporary file and renames it,
+// so a crash never leaves a partially written checkpoint behind.
+func (c *FileCheckpoint) Save(t time.Time) error {
+	data, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path)
+}
+
+const checkpointTable = "snowflake_checkpoint"
+
+type checkpointRow struct {
+	Name      string `db:"name"`
+	Timestamp int64  `db:"timestamp"` // 单位ms
+}
+
+var checkpointStruct = sqlbuilder.NewStruct(new(checkpointRow))
+
+// DBCheckpoint stores the checkpoint in the `snowflake_checkpoint` table,
+// one row per generator name.
+type DBCheckpoint struct {
+	db   *sqlx.DB
+	name string
+}
+
+func NewDBCheckpoint(db *sqlx.DB, name string) *DBCheckpoint {
+	return &DBCheckpoint{db, name}
+}
+
+func (c *DBCheckpoint) Load() (t time.Time, err error) {
+	sqlStr, args := c.selectSQL().Build()
+	var row checkpointRow
+	err = c.db.Get(&row, sqlStr, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, nil
+	}
+	if err != nil {
+		return
+	}
+	return time.UnixMilli(row.Timestamp), nil
+}
+
+func (c *DBCheckpoint) Save(t time.Time) error {
+	// 向上取整到毫秒，不能早于检查点
+	ms := t.Add(time.Millisecond - 1).UnixMilli()
+
+	sqlStr, args := c.updateSQL(ms).Build()
+	if _, err := c.db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+
+	sqlStr, args = c.countSQL().Build()
+	var num int
+	if err := c.db.Get(&num, sqlStr, args...); err != nil || num > 0 {
+		return err
+	}
+
+	sqlStr, args = c.insertSQL(ms).Build()
+	_, err := c.db.Exec(sqlStr, args...)
+	return err
+}
+
+func (c *DBCheckpoint) selectSQL() *sqlbuilder.SelectBuilder {
+	sb := checkpointStruct.SelectFrom(checkpointTable)
+	sb.Where(sb.E("name", c.name))
+	return sb
+}
+
+func (c *DBCheckpoint) updateSQL(ms int64) *sqlbuilder.UpdateBuilder {
+	ub := checkpointStruct.Update(checkpointTable, &checkpointRow{Name: c.name, Timestamp: ms})
+	ub.Where(ub.E("name", c.name))
+	return ub
+}
+
+func (c *DBCheckpoint) countSQL() *sqlbuilder.SelectBuilder {
+	sb := checkpointStruct.SelectFrom(checkpointTable)
+	sb.Select(sb.As("COUNT(*)", "count"))
+	sb.Where(sb.E("name", c.name))
+	return sb
+}
+
+func (c *DBCheckpoint) insertSQL(ms int64) *sqlbuilder.InsertBuilder {
+	return checkpointStruct.InsertInto(checkpointTable, &checkpointRow{Name: c.name, Timestamp: ms})
+}
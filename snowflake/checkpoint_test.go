@@ -0,0 +1,133 @@
+package snowflake
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type memCheckpoint struct {
+	t     time.Time
+	saves int
+	err   error
+}
+
+func (c *memCheckpoint) Load() (time.Time, error) { return c.t, nil }
+
+func (c *memCheckpoint) Save(t time.Time) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.t = t
+	c.saves++
+	return nil
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	c := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+	v, err := c.Load()
+	assert.NoError(t, err)
+	assert.True(t, v.IsZero())
+
+	now := time.Now()
+	assert.NoError(t, c.Save(now))
+	v, err = c.Load()
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(v))
+}
+
+func TestCheckpoint(t *testing.T) {
+	store := &memCheckpoint{}
+	op := &Options{MachineID: 1, Layout: SnowflakeLayout, Checkpoint: store, CheckpointInterval: 100 * time.Millisecond}
+	id, err := New(op)
+	assert.NoError(t, err)
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		last, err = id.NextID()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.saves)
+	p, _ := id.Decompose(last)
+	assert.True(t, store.t.After(p.Time))
+
+	// 重启后等待时钟越过检查点
+	checkpoint := store.t
+	id, err = New(op)
+	assert.NoError(t, err)
+	v, err := id.NextID()
+	assert.NoError(t, err)
+	p, _ = id.Decompose(v)
+	assert.False(t, p.Time.Before(checkpoint))
+	assert.Greater(t, v, last)
+}
+
+func TestCheckpoint_Rollback(t *testing.T) {
+	store := &memCheckpoint{t: time.Now().Add(time.Minute)}
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Checkpoint: store})
+	assert.NoError(t, err)
+
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
+
+func TestCheckpoint_SaveError(t *testing.T) {
+	store := &memCheckpoint{err: errors.New("disk full")}
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Checkpoint: store})
+	_, err := id.NextID()
+	assert.ErrorIs(t, err, store.err)
+}
+
+func TestNextUID_CheckpointSaveError(t *testing.T) {
+	store := &memCheckpoint{}
+	clock := NewFakeClock(testStart)
+	id, err := New(&Options{MachineID: 1, Layout: UID32Layout, Checkpoint: store, Clock: clock})
+	assert.NoError(t, err)
+	assert.NotZero(t, id.NextUID())
+	checkpoint := store.t
+
+	// 保存失败时NextUID不会越过已保存的检查点，用尽后返回0
+	store.err = errors.New("disk full")
+	clock.Add(5 * time.Minute)
+	for i := int64(0); i < UID32Layout.MaxSequence(); i++ {
+		v := id.NextUID()
+		assert.NotZero(t, v)
+		p, _ := id.Decompose(uint64(v))
+		assert.True(t, p.Time.Before(checkpoint))
+	}
+	assert.Zero(t, id.NextUID())
+
+	// 恢复后继续生成
+	store.err = nil
+	v := id.NextUID()
+	assert.NotZero(t, v)
+	p, _ := id.Decompose(uint64(v))
+	assert.True(t, clock.Now().Truncate(time.Minute).Equal(p.Time))
+}
+
+func TestCheckpoint_TruncateLayout(t *testing.T) {
+	_, err := New(&Options{MachineID: 1, Checkpoint: &memCheckpoint{}})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+}
+
+func TestDBCheckpoint_SQL(t *testing.T) {
+	c := NewDBCheckpoint(nil, "user")
+	for _, tc := range []struct {
+		b    sqlbuilder.Builder
+		want string
+	}{
+		{c.selectSQL(), "SELECT snowflake_checkpoint.name, snowflake_checkpoint.timestamp FROM snowflake_checkpoint WHERE name = 'user'"},
+		{c.updateSQL(1704038400000), "UPDATE snowflake_checkpoint SET name = 'user', timestamp = 1704038400000 WHERE name = 'user'"},
+		{c.countSQL(), "SELECT COUNT(*) AS count FROM snowflake_checkpoint WHERE name = 'user'"},
+		{c.insertSQL(1704038400000), "INSERT INTO snowflake_checkpoint (name, timestamp) VALUES ('user', 1704038400000)"},
+	} {
+		sqlStr, args := tc.b.Build()
+		sqlStr, err := sqlbuilder.MySQL.Interpolate(sqlStr, args)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, sqlStr)
+	}
+}
@@ -0,0 +1,106 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrSequenceExhausted = errors.New("snowflake: sequence exhausted")
+
+// WaitError is returned by the context aware methods, e.g. NextIDContext,
+// when the context is done while the generator waits for the clock.
+// It matches both Cause and Err with errors.Is.
+type WaitError struct {
+	Cause error // ErrSequenceExhausted 或 ErrClockMovedBackwards
+	Err   error // ctx.Err()
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("%v, gave up waiting: %v", e.Cause, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *WaitError) Is(target error) bool {
+	return errors.Is(e.Cause, target)
+}
+
+// Clock is the source of time of the generators.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the default Clock, backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FakeClock is a manually controlled Clock for deterministic tests.
+// Waiting on After moves the clock forward at once instead of sleeping.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the current time, which may be earlier to simulate a clock rollback.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Add moves the clock by d, which may be negative.
+func (c *FakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// sleepUntil 睡眠到时间戳ts开始的时刻，ctx结束时返回WaitError
+func sleepUntil(ctx context.Context, c Clock, l Layout, ts int64, cause error) error {
+	if err := ctx.Err(); err != nil {
+		return &WaitError{Cause: cause, Err: err}
+	}
+	select {
+	case <-c.After(l.Epoch.Add(time.Duration(ts) * l.TimeUnit).Sub(c.Now())):
+		return nil
+	case <-ctx.Done():
+		return &WaitError{Cause: cause, Err: ctx.Err()}
+	}
+}
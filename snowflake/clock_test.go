@@ -0,0 +1,104 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_SequenceExhausted(t *testing.T) {
+	c := NewFakeClock(testStart)
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: c})
+
+	for i := int64(0); i <= maxSequence; i++ {
+		_, err := id.NextID()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, testStart, c.Now())
+
+	// 序列号用尽，等待到下一毫秒
+	v, err := id.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, testStart.Add(time.Millisecond), c.Now())
+	p, _ := id.Decompose(v)
+	assert.True(t, testStart.Add(time.Millisecond).Equal(p.Time))
+	assert.Zero(t, p.Sequence)
+}
+
+func TestFakeClock_Rollback(t *testing.T) {
+	c := NewFakeClock(testStart)
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: c})
+	last, _ := id.NextID()
+
+	// 回拨5ms，在容忍范围内等待时钟追上
+	c.Add(-5 * time.Millisecond)
+	v, err := id.NextID()
+	assert.NoError(t, err)
+	assert.Greater(t, v, last)
+	assert.Equal(t, testStart, c.Now())
+
+	c.Add(-time.Second)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+
+	c = NewFakeClock(testStart)
+	id, _ = New(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: c, Rollback: RollbackError})
+	_, _ = id.NextID()
+	c.Add(-time.Millisecond)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+
+	c = NewFakeClock(testStart)
+	aid, _ := NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: c})
+	last, _ = aid.NextID()
+	c.Add(-5 * time.Millisecond)
+	v, err = aid.NextID()
+	assert.NoError(t, err)
+	assert.Greater(t, v, last)
+}
+
+func TestFakeClock_EpochOverflow(t *testing.T) {
+	l := UID32Layout
+	c := NewFakeClock(l.Lifetime(testStart).Expiry.Add(-time.Minute))
+	id, err := New(&Options{MachineID: 1, Layout: l, Clock: c})
+	assert.NoError(t, err)
+
+	_, err = id.NextID()
+	assert.NoError(t, err)
+
+	c.Add(time.Minute)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrEpochOverflow)
+	assert.Zero(t, id.Lifetime().Remaining)
+
+	_, err = New(&Options{MachineID: 1, Layout: l, Clock: c})
+	assert.ErrorIs(t, err, ErrEpochOverflow)
+}
+
+func TestFakeClock_MultiNode(t *testing.T) {
+	c := NewFakeClock(testStart)
+	ids := make(map[uint64]int64)
+	for mid := int64(1); mid <= UIDLayout.MaxMachineID(); mid++ {
+		id, _ := New(&Options{MachineID: mid, Clock: c})
+		for i := 0; i <= int(maxSequence); i++ {
+			v, err := id.NextID()
+			assert.NoError(t, err)
+			if other, exist := ids[v]; exist {
+				t.Fatalf("machine %d and %d generated the same id %d", other, mid, v)
+			}
+			ids[v] = mid
+		}
+	}
+	// 所有机器都在同一毫秒内生成
+	assert.Equal(t, testStart, c.Now())
+
+	// UIDLayout的时间戳约32s循环一次，此后会生成重复的ID
+	id, _ := New(&Options{MachineID: 1, Clock: c})
+	v1, _ := id.NextID()
+	c.Add(time.Duration(1<<15) * time.Millisecond)
+	v2, _ := id.NextID()
+	assert.Equal(t, v1, v2)
+}
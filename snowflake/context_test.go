@@ -0,0 +1,155 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stuckClock 的时间只能手动调整，After永远不会触发
+type stuckClock struct {
+	*FakeClock
+}
+
+func (stuckClock) After(time.Duration) <-chan time.Time {
+	return nil
+}
+
+func TestNextIDContext_SequenceExhausted(t *testing.T) {
+	clock := stuckClock{NewFakeClock(testStart)}
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: clock})
+	assert.NoError(t, err)
+
+	ids := make(map[uint64]bool)
+	for i := int64(0); i <= maxSequence; i++ {
+		v, err := id.NextID()
+		assert.NoError(t, err)
+		ids[v] = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = id.NextIDContext(ctx)
+	var we *WaitError
+	assert.ErrorAs(t, err, &we)
+	assert.ErrorIs(t, err, ErrSequenceExhausted)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 放弃等待后生成器的状态不变
+	clock.Add(time.Millisecond)
+	v, err := id.NextIDContext(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ids[v], "generated the repeated id: %d", v)
+	p, _ := id.Decompose(v)
+	assert.Equal(t, int64(0), p.Sequence)
+}
+
+func TestNextIDContext_Rollback(t *testing.T) {
+	clock := stuckClock{NewFakeClock(testStart)}
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout, RollbackTolerance: -1, Clock: clock})
+	assert.NoError(t, err)
+	_, err = id.NextID()
+	assert.NoError(t, err)
+
+	clock.Add(-time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = id.NextIDContext(ctx)
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, errors.Is(err, ErrSequenceExhausted))
+}
+
+func TestAtomicID_NextIDContext(t *testing.T) {
+	clock := stuckClock{NewFakeClock(testStart)}
+	id, err := NewAtomic(&Options{MachineID: 1, Layout: SnowflakeLayout, RollbackTolerance: -1, Clock: clock})
+	assert.NoError(t, err)
+	for i := int64(0); i <= maxSequence; i++ {
+		_, err = id.NextID()
+		assert.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = id.NextIDContext(ctx)
+	assert.ErrorIs(t, err, ErrSequenceExhausted)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	clock.Add(-time.Second)
+	_, err = id.NextIDContext(ctx)
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
+
+func TestNextIDContext_LockReleased(t *testing.T) {
+	clock := stuckClock{NewFakeClock(testStart)}
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout, RollbackTolerance: -1, Clock: clock})
+	assert.NoError(t, err)
+	_, err = id.NextID()
+	assert.NoError(t, err)
+
+	// 没有ctx的调用一直等待时钟追上
+	clock.Add(-time.Hour)
+	stuck := make(chan error, 1)
+	go func() {
+		_, err := id.NextID()
+		stuck <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// 带ctx的调用仍然在截止时间返回
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = id.NextIDContext(ctx)
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	select {
+	case err := <-stuck:
+		t.Fatalf("NextID should still be waiting, got %v", err)
+	default:
+	}
+}
+
+// gateClock 的After在关闭gate后才触发
+type gateClock struct {
+	*FakeClock
+	gate chan time.Time
+}
+
+func (c gateClock) After(time.Duration) <-chan time.Time {
+	return c.gate
+}
+
+func TestNextN_LockHeld(t *testing.T) {
+	clock := gateClock{NewFakeClock(testStart), make(chan time.Time)}
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout, Clock: clock})
+	assert.NoError(t, err)
+
+	done := make(chan []uint64, 1)
+	go func() {
+		ids, err := id.NextN(int(maxSequence) + 2)
+		assert.NoError(t, err)
+		done <- ids
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// 等待下一毫秒时仍然持有锁，其他调用不会插入
+	assert.False(t, id.TryLock())
+	clock.Add(time.Millisecond)
+	close(clock.gate)
+	ids := <-done
+	for i := 1; i < len(ids)-1; i++ {
+		assert.Equal(t, ids[i-1]+1, ids[i])
+	}
+	p, _ := id.Decompose(ids[len(ids)-1])
+	assert.Zero(t, p.Sequence)
+
+	v, err := id.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, ids[len(ids)-1]+1, v)
+}
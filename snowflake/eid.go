@@ -0,0 +1,150 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultEIDPrefix is the vanity prefix of enterprise IDs.
+const DefaultEIDPrefix = "888"
+
+var ErrInvalidEID = errors.New("snowflake: invalid enterprise id")
+
+type EIDOptions struct {
+	Options           // 默认Layout为UID32Layout
+	Prefix     string // 只能是数字，默认为DefaultEIDPrefix
+	CheckDigit bool   // 末尾追加Luhn校验位
+}
+
+// EIDGenerator generates enterprise IDs: a decimal vanity prefix followed by
+// a fixed width snowflake ID and an optional Luhn check digit, e.g.
+//
+//	888 0012345678 9
+//
+// The snowflake ID makes them unique across calls and machines.
+type EIDGenerator struct {
+	id     *ID
+	prefix string
+	width  int    // 雪花ID的十进制位数
+	base   uint64 // prefix * 10^width
+	check  bool
+}
+
+func NewEIDGenerator(op *EIDOptions) (*EIDGenerator, error) {
+	gop := op.Options
+	if gop.Layout == (Layout{}) {
+		gop.Layout = UID32Layout
+	}
+	if gop.Layout.Truncate {
+		return nil, fmt.Errorf("%w: enterprise ids can not use a truncating layout", ErrInvalidLayout)
+	}
+	id, err := New(&gop)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := op.Prefix
+	if prefix == "" {
+		prefix = DefaultEIDPrefix
+	}
+	if strings.Trim(prefix, "0123456789") != "" || prefix[0] == '0' {
+		return nil, fmt.Errorf("snowflake: invalid enterprise id prefix %q", prefix)
+	}
+
+	maxID := uint64(1)<<gop.Layout.Bits() - 1
+	g := &EIDGenerator{
+		id:     id,
+		prefix: prefix,
+		width:  len(strconv.FormatUint(maxID, 10)),
+		check:  op.CheckDigit,
+	}
+	digits := len(prefix) + g.width
+	if g.check {
+		digits++
+	}
+	// 19位以内的十进制数一定不会超出uint64
+	if digits > 19 {
+		return nil, fmt.Errorf("snowflake: enterprise ids of %d digits overflow uint64", digits)
+	}
+	g.base, _ = strconv.ParseUint(prefix+strings.Repeat("0", g.width), 10, 64)
+	return g, nil
+}
+
+// Next returns the next enterprise ID.
+func (g *EIDGenerator) Next() (uint64, error) {
+	return g.NextContext(context.Background())
+}
+
+// NextContext is like Next but honors ctx like ID.NextIDContext.
+func (g *EIDGenerator) NextContext(ctx context.Context) (uint64, error) {
+	v, err := g.id.NextIDContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return g.eid(v), nil
+}
+
+// NextIDContext is NextContext, so enterprise IDs can be registered.
+func (g *EIDGenerator) NextIDContext(ctx context.Context) (uint64, error) {
+	return g.NextContext(ctx)
+}
+
+// Decompose validates eid and splits its snowflake ID into the parts.
+func (g *EIDGenerator) Decompose(eid uint64) (Parts, error) {
+	if err := g.Validate(eid); err != nil {
+		return Parts{}, err
+	}
+	if g.check {
+		eid /= 10
+	}
+	return g.id.Decompose(eid - g.base)
+}
+
+func (g *EIDGenerator) eid(v uint64) uint64 {
+	eid := g.base + v
+	if g.check {
+		eid = eid*10 + luhnDigit(eid)
+	}
+	return eid
+}
+
+// Validate reports whether eid has the prefix, length and check digit of
+// the enterprise IDs generated by g, which catches most typos.
+func (g *EIDGenerator) Validate(eid uint64) error {
+	s := strconv.FormatUint(eid, 10)
+	n := len(g.prefix) + g.width
+	if g.check {
+		n++
+	}
+	if len(s) != n || !strings.HasPrefix(s, g.prefix) {
+		return fmt.Errorf("%w: %d", ErrInvalidEID, eid)
+	}
+	if g.check && !ValidLuhn(eid) {
+		return fmt.Errorf("%w: %d", ErrChecksum, eid)
+	}
+	return nil
+}
+
+// ValidLuhn reports whether the last digit of v is its Luhn check digit.
+func ValidLuhn(v uint64) bool {
+	return luhnDigit(v/10) == v%10
+}
+
+// luhnDigit 从最低位开始，每隔一位乘2（大于9则减9）后求和
+func luhnDigit(v uint64) uint64 {
+	var sum uint64
+	for double := true; v > 0; v /= 10 {
+		d := v % 10
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
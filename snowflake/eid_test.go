@@ -0,0 +1,80 @@
+package snowflake
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEIDGenerator(t *testing.T) {
+	g, err := NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1}, CheckDigit: true})
+	assert.NoError(t, err)
+
+	v, err := g.Next()
+	assert.NoError(t, err)
+	s := strconv.FormatUint(v, 10)
+	assert.True(t, strings.HasPrefix(s, DefaultEIDPrefix), s)
+	assert.Len(t, s, 14)
+	assert.True(t, ValidLuhn(v))
+	assert.NoError(t, g.Validate(v))
+
+	// 单个数字输错
+	for i := 1; i < len(s); i++ {
+		typo := []byte(s)
+		typo[i] = '0' + (typo[i]-'0'+1)%10
+		n, _ := strconv.ParseUint(string(typo), 10, 64)
+		assert.Error(t, g.Validate(n), string(typo))
+	}
+	assert.ErrorIs(t, g.Validate(v/10), ErrInvalidEID)
+}
+
+func TestEIDGenerator_Unique(t *testing.T) {
+	l := SnowflakeLayout
+	l.NodeBits = 5
+	ids := make(map[uint64]struct{})
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for mid := int64(1); mid <= 2; mid++ {
+		g, err := NewEIDGenerator(&EIDOptions{Options: Options{MachineID: mid, Layout: l}, Prefix: "6"})
+		assert.NoError(t, err)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < HundredK; i++ {
+				v, err := g.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if _, exist := ids[v]; exist {
+					t.Errorf("generated the repeated eid: %d", v)
+				}
+				ids[v] = struct{}{}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewEIDGenerator_Invalid(t *testing.T) {
+	_, err := NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1}, Prefix: "08"})
+	assert.Error(t, err)
+	_, err = NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1}, Prefix: "8a"})
+	assert.Error(t, err)
+	_, err = NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1, Layout: UIDLayout}})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+	_, err = NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1, Layout: SnowflakeLayout}, CheckDigit: true})
+	assert.Error(t, err)
+}
+
+func TestValidLuhn(t *testing.T) {
+	assert.True(t, ValidLuhn(79927398713))
+	assert.False(t, ValidLuhn(79927398710))
+	assert.Equal(t, uint64(3), luhnDigit(7992739871))
+}
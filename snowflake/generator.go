@@ -0,0 +1,73 @@
+package snowflake
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Generator generates unique IDs in their string form, so services can switch
+// the strategy by configuration and tests can inject a fake one.
+type Generator interface {
+	Generate() (string, error)
+}
+
+// GeneratorFunc adapts a function to Generator, e.g. to fake IDs in tests.
+type GeneratorFunc func() (string, error)
+
+func (f GeneratorFunc) Generate() (string, error) {
+	return f()
+}
+
+type Strategy string
+
+const (
+	StrategySnowflake32 Strategy = "snowflake32" // 默认为UIDLayout
+	StrategySnowflake64 Strategy = "snowflake64" // 默认为SnowflakeLayout
+	StrategyUUIDv7      Strategy = "uuidv7"
+	StrategyULID        Strategy = "ulid"
+)
+
+// NewGenerator creates the generator of the strategy. The options only apply
+// to the snowflake strategies, op may be nil for the others.
+func NewGenerator(s Strategy, op *Options) (Generator, error) {
+	switch s {
+	case StrategySnowflake32, StrategySnowflake64:
+		gop := Options{}
+		if op != nil {
+			gop = *op
+		}
+		if gop.Layout == (Layout{}) {
+			gop.Layout = UIDLayout
+			if s == StrategySnowflake64 {
+				gop.Layout = SnowflakeLayout
+			}
+		}
+		if s == StrategySnowflake32 && gop.Layout.Bits() > 32 {
+			return nil, fmt.Errorf("%w: %d bits exceed 32 bits", ErrInvalidLayout, gop.Layout.Bits())
+		}
+		return New(&gop)
+	case StrategyUUIDv7:
+		return NewUUIDv7Generator(), nil
+	case StrategyULID:
+		return NewULIDGenerator(), nil
+	}
+	return nil, fmt.Errorf("snowflake: unknown strategy %q", s)
+}
+
+// Generate returns NextID in decimal.
+func (id *ID) Generate() (string, error) {
+	v, err := id.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(v, 10), nil
+}
+
+// Generate returns Next in decimal.
+func (g *EIDGenerator) Generate() (string, error) {
+	v, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(v, 10), nil
+}
@@ -0,0 +1,82 @@
+package snowflake
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGenerator(t *testing.T) {
+	less := map[Strategy]func(a, b string) bool{
+		StrategySnowflake32: func(a, b string) bool { return a != b },
+		StrategySnowflake64: func(a, b string) bool {
+			x, _ := strconv.ParseUint(a, 10, 64)
+			y, _ := strconv.ParseUint(b, 10, 64)
+			return x < y
+		},
+		StrategyUUIDv7: func(a, b string) bool { return a < b },
+		StrategyULID:   func(a, b string) bool { return a < b },
+	}
+	for s, less := range less {
+		g, err := NewGenerator(s, &Options{MachineID: 1})
+		assert.NoError(t, err)
+
+		seen := make(map[string]bool)
+		last := ""
+		for i := 0; i < HundredK; i++ {
+			v, err := g.Generate()
+			assert.NoError(t, err)
+			if seen[v] || (last != "" && !less(last, v)) {
+				t.Fatalf("%s: %q generated after %q", s, v, last)
+			}
+			seen[v] = true
+			last = v
+		}
+	}
+
+	_, err := NewGenerator("auto_increment", nil)
+	assert.Error(t, err)
+	_, err = NewGenerator(StrategySnowflake32, &Options{Layout: SnowflakeLayout})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+}
+
+func TestUUIDv7Generator(t *testing.T) {
+	u, err := NewUUIDv7Generator().Next()
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), u.Version())
+	assert.Equal(t, uuid.RFC4122, u.Variant())
+
+	parsed, err := uuid.Parse(u.String())
+	assert.NoError(t, err)
+	assert.Equal(t, u, parsed)
+}
+
+func TestULIDGenerator(t *testing.T) {
+	g := NewULIDGenerator()
+	u, err := g.Next()
+	assert.NoError(t, err)
+	assert.Len(t, u.String(), 26)
+	assert.Equal(t, ULID{}.String(), "00000000000000000000000000")
+	assert.Equal(t, ULID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}.String(),
+		"7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+	// 同一毫秒内随机部分溢出
+	for i := 6; i < 16; i++ {
+		g.last[i] = 0xff
+	}
+	copy(g.last[:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	_, err = g.Next()
+	assert.ErrorIs(t, err, ErrRandomOverflow)
+}
+
+func TestGeneratorFunc(t *testing.T) {
+	n := 0
+	var g Generator = GeneratorFunc(func() (string, error) {
+		n++
+		return strconv.Itoa(n), nil
+	})
+	v, _ := g.Generate()
+	assert.Equal(t, "1", v)
+}
@@ -0,0 +1,280 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var (
+	ErrInvalidLayout = errors.New("snowflake: invalid layout")
+	ErrInvalidID     = errors.New("snowflake: invalid id")
+)
+
+// Layout describes how an ID is assembled, from the highest bits to the lowest:
+//
+//	| timestamp | datacenter id | machine id | sequence |
+//
+// The datacenter id is optional, a layout without DatacenterBits is the
+// same as before it was added.
+type Layout struct {
+	TimestampBits  uint8         // 时间戳所占的位数
+	TimeUnit       time.Duration // 时间戳的粒度
+	DatacenterBits uint8         // 数据中心（地域）id所占的位数
+	NodeBits       uint8         // 机器id所占的位数
+	SequenceBits   uint8         // 序列号所占的位数
+	Epoch          time.Time     // 起始时间
+
+	// Truncate keeps only the low TimestampBits of the elapsed time instead of
+	// failing once the timestamp no longer fits. The time component then wraps,
+	// so uniqueness is only guaranteed within one wrap period.
+	Truncate bool
+}
+
+var (
+	// UIDLayout is the layout of the legacy 32bits UID generated by ID.NextUID:
+	// the millisecond timestamp is truncated to 15 bits and wraps every ~32s.
+	UIDLayout = Layout{
+		TimestampBits: 32 - machineIDBits - sequenceBits,
+		TimeUnit:      time.Millisecond,
+		NodeBits:      machineIDBits,
+		SequenceBits:  sequenceBits,
+		Epoch:         time.UnixMilli(epoch),
+		Truncate:      true,
+	}
+
+	// UID32Layout is a 32bits layout which never wraps: 23 bits of minutes
+	// (~15.9 years since 2024-01-01, see Layout.Lifetime), 4 bits of machine id
+	// and 5 bits of sequence, i.e. 32 IDs per minute per machine.
+	UID32Layout = Layout{
+		TimestampBits: 23,
+		TimeUnit:      time.Minute,
+		NodeBits:      4,
+		SequenceBits:  5,
+		Epoch:         time.UnixMilli(1704038400000), // 2024-01-01 00:00:00
+	}
+
+	// SnowflakeLayout is the classic 64bits snowflake layout: 41 bits of
+	// milliseconds (~69 years), 10 bits of machine id and 12 bits of sequence.
+	SnowflakeLayout = Layout{
+		TimestampBits: 41,
+		TimeUnit:      time.Millisecond,
+		NodeBits:      10,
+		SequenceBits:  12,
+		Epoch:         time.UnixMilli(epoch),
+	}
+
+	// SnowflakeDCLayout is SnowflakeLayout with the 10 bits of machine id split
+	// into 5 bits of datacenter id and 5 bits of machine id, like Twitter's
+	// original snowflake. SnowflakeLayout IDs of machine ids below 32 decode
+	// as datacenter 0.
+	SnowflakeDCLayout = Layout{
+		TimestampBits:  41,
+		TimeUnit:       time.Millisecond,
+		DatacenterBits: 5,
+		NodeBits:       5,
+		SequenceBits:   12,
+		Epoch:          time.UnixMilli(epoch),
+	}
+)
+
+// ParseLayout returns the predefined layout of the name: "uid" (UIDLayout),
+// "uid32" (UID32Layout), "snowflake" (SnowflakeLayout) or "snowflake-dc"
+// (SnowflakeDCLayout).
+func ParseLayout(name string) (Layout, error) {
+	switch name {
+	case "uid":
+		return UIDLayout, nil
+	case "uid32":
+		return UID32Layout, nil
+	case "snowflake":
+		return SnowflakeLayout, nil
+	case "snowflake-dc":
+		return SnowflakeDCLayout, nil
+	}
+	return Layout{}, fmt.Errorf("%w: unknown layout %q", ErrInvalidLayout, name)
+}
+
+// Parts holds the fields embedded in an ID.
+type Parts struct {
+	Time         time.Time // 生成时间，精度为Layout.TimeUnit
+	Timestamp    int64     // 自Epoch起经过的时间单位数
+	DatacenterID int64
+	MachineID    int64
+	Sequence     int64
+}
+
+// Decompose splits id into the creation time, datacenter id, machine id and sequence.
+// It fails with ErrInvalidID if id cannot have been generated with the layout,
+// e.g. it is wider than the layout or its timestamp is in the future.
+//
+// Truncating layouts only keep the timestamp modulo the wrap period, so the
+// returned time is the latest one matching the bits that is not after now.
+func (l Layout) Decompose(id uint64) (Parts, error) {
+	return l.DecomposeAt(id, time.Now())
+}
+
+// DecomposeAt is like Decompose but as seen at now, e.g. when auditing IDs
+// against a fixed point in time.
+func (l Layout) DecomposeAt(id uint64, now time.Time) (Parts, error) {
+	return l.decompose(id, now)
+}
+
+func (l Layout) decompose(id uint64, t time.Time) (p Parts, err error) {
+	if err = l.Validate(); err != nil {
+		return
+	}
+	if l.Bits() < 64 && id>>l.Bits() != 0 {
+		return p, fmt.Errorf("%w: %d is wider than %d bits", ErrInvalidID, id, l.Bits())
+	}
+	p.Sequence = int64(id & uint64(l.MaxSequence()))
+	p.MachineID = int64(id>>l.SequenceBits) & l.MaxMachineID()
+	p.DatacenterID = int64(id>>(l.NodeBits+l.SequenceBits)) & l.MaxDatacenterID()
+	p.Timestamp = int64(id >> l.timestampShift())
+
+	now := l.timestamp(t)
+	if p.Timestamp > now {
+		return p, fmt.Errorf("%w: timestamp of %d is in the future", ErrInvalidID, id)
+	}
+	if l.Truncate {
+		period := l.maxTimestamp() + 1
+		p.Timestamp += (now - p.Timestamp) / period * period
+	}
+	p.Time = l.Epoch.Add(time.Duration(p.Timestamp) * l.TimeUnit)
+	return p, nil
+}
+
+// Lifetime reports how long a layout can generate unique IDs.
+type Lifetime struct {
+	Period    time.Duration // 时间戳可表示的总时长，截断的Layout每个Period循环一次
+	Expiry    time.Time     // 时间戳用尽的时间，截断的Layout为零值
+	Remaining time.Duration // 距Expiry的剩余时间，截断的Layout为Period
+	Capacity  uint64        // 每台机器剩余可生成的ID数量
+}
+
+// Lifetime returns the lifetime of the layout as seen at now.
+func (l Layout) Lifetime(now time.Time) (lt Lifetime) {
+	units := uint64(l.maxTimestamp()) + 1
+	lt.Period = mulDuration(units, l.TimeUnit)
+	if l.Truncate {
+		lt.Remaining = lt.Period
+		lt.Capacity = mulUint64(units, uint64(l.MaxSequence())+1)
+		return
+	}
+
+	lt.Expiry = l.Epoch.Add(lt.Period)
+	lt.Remaining = lt.Expiry.Sub(now)
+	if lt.Remaining < 0 {
+		lt.Remaining = 0
+	}
+	if ts := l.timestamp(now); ts < int64(units) {
+		if ts > 0 {
+			units -= uint64(ts)
+		}
+		lt.Capacity = mulUint64(units, uint64(l.MaxSequence())+1)
+	}
+	return
+}
+
+func mulDuration(n uint64, d time.Duration) time.Duration {
+	v := mulUint64(n, uint64(d))
+	if v > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(v)
+}
+
+func mulUint64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+// Validate reports whether the layout can be used to generate IDs.
+func (l Layout) Validate() error {
+	switch {
+	case l.TimestampBits == 0:
+		return fmt.Errorf("%w: timestamp bits must be positive", ErrInvalidLayout)
+	case l.SequenceBits == 0:
+		return fmt.Errorf("%w: sequence bits must be positive", ErrInvalidLayout)
+	case l.TimeUnit <= 0:
+		return fmt.Errorf("%w: time unit must be positive", ErrInvalidLayout)
+	case l.Epoch.IsZero():
+		return fmt.Errorf("%w: epoch is not set", ErrInvalidLayout)
+	case l.bits() > 64:
+		return fmt.Errorf("%w: %d bits exceed 64 bits", ErrInvalidLayout, l.bits())
+	}
+	return nil
+}
+
+// Bits returns the total number of bits used by an ID.
+func (l Layout) Bits() uint8 {
+	return l.TimestampBits + l.DatacenterBits + l.NodeBits + l.SequenceBits
+}
+
+// bits 与Bits相同，但不会溢出，用于校验
+func (l Layout) bits() int {
+	return int(l.TimestampBits) + int(l.DatacenterBits) + int(l.NodeBits) + int(l.SequenceBits)
+}
+
+func (l Layout) timestampShift() uint8 {
+	return l.DatacenterBits + l.NodeBits + l.SequenceBits
+}
+
+// MaxDatacenterID returns the largest datacenter id the layout can hold,
+// 0 if the layout has no datacenter id.
+func (l Layout) MaxDatacenterID() int64 {
+	return -1 ^ (-1 << l.DatacenterBits)
+}
+
+// MaxMachineID returns the largest machine id the layout can hold.
+func (l Layout) MaxMachineID() int64 {
+	return -1 ^ (-1 << l.NodeBits)
+}
+
+// MaxSequence returns the largest sequence the layout can hold.
+func (l Layout) MaxSequence() int64 {
+	return -1 ^ (-1 << l.SequenceBits)
+}
+
+func (l Layout) maxTimestamp() int64 {
+	if l.TimestampBits >= 63 {
+		return 1<<63 - 1
+	}
+	return -1 ^ (-1 << l.TimestampBits)
+}
+
+// timestamp returns the number of time units elapsed since the epoch.
+func (l Layout) timestamp(t time.Time) int64 {
+	if l.TimeUnit == time.Millisecond {
+		return t.UnixMilli() - l.Epoch.UnixMilli()
+	}
+	return int64(t.Sub(l.Epoch) / l.TimeUnit)
+}
+
+// node 拼接数据中心id和机器id，作为compose的mid
+func (l Layout) node(dc, mid int64) int64 {
+	return dc<<l.NodeBits | mid
+}
+
+// compose 拼接时间戳、机器id和序列号，mid可以是node拼接的数据中心id和机器id。
+// 时间戳在高位，中间位为机器id，低位为序列号。
+func (l Layout) compose(ts, mid, seq int64) (uint64, error) {
+	if ts < 0 {
+		return 0, fmt.Errorf("snowflake: time is before the epoch %s", l.Epoch)
+	}
+	if ts > l.maxTimestamp() {
+		if !l.Truncate {
+			return 0, ErrEpochOverflow
+		}
+		ts &= l.maxTimestamp()
+	}
+	v := uint64(ts)<<l.timestampShift() |
+		uint64(mid)<<l.SequenceBits |
+		uint64(seq)
+	return v, nil
+}
@@ -0,0 +1,200 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout_Validate(t *testing.T) {
+	assert.NoError(t, UIDLayout.Validate())
+	assert.NoError(t, SnowflakeLayout.Validate())
+	assert.Equal(t, uint8(32), UIDLayout.Bits())
+	assert.Equal(t, uint8(63), SnowflakeLayout.Bits())
+
+	l := SnowflakeLayout
+	l.NodeBits = 20
+	assert.True(t, errors.Is(l.Validate(), ErrInvalidLayout))
+
+	l = SnowflakeLayout
+	l.TimeUnit = 0
+	assert.True(t, errors.Is(l.Validate(), ErrInvalidLayout))
+
+	assert.NoError(t, SnowflakeDCLayout.Validate())
+	assert.Equal(t, uint8(63), SnowflakeDCLayout.Bits())
+	l = SnowflakeDCLayout
+	l.DatacenterBits = 10
+	assert.True(t, errors.Is(l.Validate(), ErrInvalidLayout))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Options{MachineID: 32})
+	assert.Error(t, err)
+
+	id, err := New(&Options{MachineID: 1023, Layout: SnowflakeLayout})
+	assert.NoError(t, err)
+	assert.Equal(t, SnowflakeLayout, id.Layout())
+
+	id, err = New(&Options{MachineID: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, UIDLayout, id.Layout())
+}
+
+func TestID_NextID(t *testing.T) {
+	id, err := New(&Options{MachineID: 7, Layout: SnowflakeLayout})
+	assert.NoError(t, err)
+
+	ids := make(map[uint64]struct{})
+	var last uint64
+	for i := 0; i < M; i++ {
+		v, err := id.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, exist := ids[v]; exist {
+			t.Fatalf("generated the %dth repeated id: %d", i, v)
+		}
+		if v <= last {
+			t.Fatalf("id %d is not greater than %d", v, last)
+		}
+		ids[v] = struct{}{}
+		last = v
+	}
+}
+
+func TestID_NextID_EpochOverflow(t *testing.T) {
+	l := SnowflakeLayout
+	l.TimestampBits = 10
+	l.Epoch = time.Now()
+	id, err := New(&Options{MachineID: 1, Layout: l})
+	assert.NoError(t, err)
+
+	id.layout.Epoch = l.Epoch.Add(-time.Hour)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrEpochOverflow)
+}
+
+func TestLayout_Decompose(t *testing.T) {
+	for _, l := range []Layout{UIDLayout, SnowflakeLayout} {
+		id, err := New(&Options{MachineID: 9, Layout: l})
+		assert.NoError(t, err)
+
+		before := time.Now().Truncate(time.Millisecond)
+		for i := int64(0); i < 3; i++ {
+			v, err := id.NextID()
+			assert.NoError(t, err)
+
+			p, err := id.Decompose(v)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(9), p.MachineID)
+			assert.False(t, p.Time.Before(before))
+			assert.False(t, p.Time.After(time.Now()))
+			if p.Sequence != id.sequence {
+				t.Errorf("sequence got %d, want %d", p.Sequence, id.sequence)
+			}
+		}
+	}
+}
+
+func TestLayout_Decompose_Invalid(t *testing.T) {
+	_, err := UIDLayout.Decompose(1 << 32)
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	future := uint64(SnowflakeLayout.timestamp(time.Now().Add(time.Hour))) << 22
+	_, err = SnowflakeLayout.Decompose(future)
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestLayout_Lifetime(t *testing.T) {
+	assert.Equal(t, uint8(32), UID32Layout.Bits())
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	lt := UID32Layout.Lifetime(now)
+	assert.Equal(t, time.Duration(1<<23)*time.Minute, lt.Period)
+	assert.Equal(t, 2039, lt.Expiry.Year())
+	assert.Equal(t, lt.Expiry.Sub(now), lt.Remaining)
+	assert.Equal(t, uint64(1<<23-UID32Layout.timestamp(now))*32, lt.Capacity)
+
+	lt = UID32Layout.Lifetime(lt.Expiry.Add(time.Hour))
+	assert.Zero(t, lt.Remaining)
+	assert.Zero(t, lt.Capacity)
+
+	lt = UIDLayout.Lifetime(now)
+	assert.True(t, lt.Expiry.IsZero())
+	assert.Equal(t, time.Duration(1<<15)*time.Millisecond, lt.Remaining)
+}
+
+func TestNew_Expired(t *testing.T) {
+	l := UID32Layout
+	l.TimestampBits = 10
+	l.NodeBits = 17
+	_, err := New(&Options{MachineID: 1, Layout: l})
+	assert.ErrorIs(t, err, ErrEpochOverflow)
+}
+
+func TestID_NextID_CoarseUnit(t *testing.T) {
+	l := Layout{TimestampBits: 40, TimeUnit: 20 * time.Millisecond, SequenceBits: 2, Epoch: UID32Layout.Epoch}
+	id, err := New(&Options{Layout: l})
+	assert.NoError(t, err)
+
+	var last uint64
+	for i := 0; i < 12; i++ {
+		v, err := id.NextID()
+		assert.NoError(t, err)
+		assert.Greater(t, v, last)
+		last = v
+	}
+}
+
+func TestLayout_Datacenter(t *testing.T) {
+	_, err := New(&Options{DatacenterID: 1, MachineID: 1, Layout: SnowflakeLayout})
+	assert.ErrorIs(t, err, ErrInvalidDatacenterID)
+	_, err = New(&Options{DatacenterID: 32, MachineID: 1, Layout: SnowflakeDCLayout})
+	assert.ErrorIs(t, err, ErrInvalidDatacenterID)
+	_, err = New(&Options{DatacenterID: 1, MachineID: 32, Layout: SnowflakeDCLayout})
+	assert.ErrorIs(t, err, ErrInvalidMachineID)
+
+	// 不同数据中心相同机器ID生成的ID不会重复
+	clock := NewFakeClock(testStart)
+	ids := make(map[uint64]bool)
+	for dc := int64(0); dc <= SnowflakeDCLayout.MaxDatacenterID(); dc++ {
+		id, err := New(&Options{DatacenterID: dc, MachineID: 31, Layout: SnowflakeDCLayout, Clock: clock})
+		assert.NoError(t, err)
+		v, err := id.NextID()
+		assert.NoError(t, err)
+		assert.False(t, ids[v], "generated the repeated id: %d", v)
+		ids[v] = true
+
+		p, err := id.Decompose(v)
+		assert.NoError(t, err)
+		assert.Equal(t, dc, p.DatacenterID)
+		assert.Equal(t, int64(31), p.MachineID)
+		assert.True(t, p.Time.Equal(testStart.Truncate(time.Millisecond)))
+	}
+
+	// 机器ID小于32的SnowflakeLayout ID可以按SnowflakeDCLayout解析
+	id, _ := New(&Options{MachineID: 9, Layout: SnowflakeLayout, Clock: clock})
+	v, _ := id.NextID()
+	p, err := SnowflakeDCLayout.decompose(v, clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), p.DatacenterID)
+	assert.Equal(t, int64(9), p.MachineID)
+}
+
+func TestNextUID_WideLayout(t *testing.T) {
+	id, err := New(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	assert.NoError(t, err)
+	_, err = id.TryNextUID()
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+	_, err = id.NextUIDContext(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+	assert.Panics(t, func() { id.NextUID() })
+
+	id, err = New(&Options{MachineID: 1, Layout: UID32Layout})
+	assert.NoError(t, err)
+	_, err = id.TryNextUID()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,165 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMachineID    = errors.New("snowflake: invalid machine id")
+	ErrInvalidDatacenterID = errors.New("snowflake: invalid datacenter id")
+)
+
+// DefaultPodIPEnv is the environment variable IPMachineID reads the pod IP
+// from, usually set with the downward API (status.podIP).
+const DefaultPodIPEnv = "POD_IP"
+
+// MachineIDSource provides the machine id of a generator, see
+// Options.MachineIDSource.
+type MachineIDSource interface {
+	MachineID() (int64, error)
+}
+
+// MachineIDFunc adapts a function to MachineIDSource, e.g. a redislease.Lease:
+//
+//	snowflake.MachineIDFunc(func() (int64, error) { return lease.MachineID(), nil })
+type MachineIDFunc func() (int64, error)
+
+func (f MachineIDFunc) MachineID() (int64, error) {
+	return f()
+}
+
+// StaticMachineID is an explicit machine id.
+type StaticMachineID int64
+
+func (s StaticMachineID) MachineID() (int64, error) {
+	return int64(s), nil
+}
+
+// EnvMachineID reads the machine id from the environment variable of its name.
+type EnvMachineID string
+
+func (e EnvMachineID) MachineID() (int64, error) {
+	v, ok := os.LookupEnv(string(e))
+	if !ok {
+		return 0, fmt.Errorf("%w: environment variable %s is not set", ErrInvalidMachineID, string(e))
+	}
+	mid, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s=%q is not a number", ErrInvalidMachineID, string(e), v)
+	}
+	return mid, nil
+}
+
+// HostnameOrdinal takes the machine id from the ordinal suffix of the
+// hostname, e.g. 2 for "idserver-2", as given to the pods of a Kubernetes
+// StatefulSet. Offset is added to the ordinal, e.g. to skip machine id 0.
+type HostnameOrdinal struct {
+	Hostname string // 默认为os.Hostname()
+	Offset   int64
+}
+
+func (h HostnameOrdinal) MachineID() (int64, error) {
+	name := h.Hostname
+	if name == "" {
+		var err error
+		if name, err = os.Hostname(); err != nil {
+			return 0, err
+		}
+	}
+	// 只取第一段，兼容 idserver-2.idserver.default.svc 这样的FQDN
+	name, _, _ = strings.Cut(name, ".")
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, fmt.Errorf("%w: hostname %q has no ordinal", ErrInvalidMachineID, name)
+	}
+	n, err := strconv.ParseInt(name[i+1:], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: hostname %q has no ordinal", ErrInvalidMachineID, name)
+	}
+	return n + h.Offset, nil
+}
+
+// IPMachineID takes the low Bits bits of an IP address as the machine id.
+// It only avoids collisions if the addresses of all the machines differ in
+// these bits, e.g. Bits 8 for pods within a /24 subnet.
+type IPMachineID struct {
+	// IP 默认读取环境变量DefaultPodIPEnv，未设置时使用第一个非回环地址
+	IP   net.IP
+	Bits uint8
+}
+
+func (s IPMachineID) MachineID() (int64, error) {
+	if s.Bits == 0 || s.Bits > 32 {
+		return 0, fmt.Errorf("%w: ip bits must be in [1, 32], got %d", ErrInvalidMachineID, s.Bits)
+	}
+	ip := s.IP
+	if ip == nil {
+		var err error
+		if ip, err = localIP(); err != nil {
+			return 0, err
+		}
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if len(ip) < 4 {
+		return 0, fmt.Errorf("%w: invalid ip %v", ErrInvalidMachineID, ip)
+	}
+	low := uint32(ip[len(ip)-4])<<24 | uint32(ip[len(ip)-3])<<16 | uint32(ip[len(ip)-2])<<8 | uint32(ip[len(ip)-1])
+	return int64(low & (1<<s.Bits - 1)), nil
+}
+
+func localIP() (net.IP, error) {
+	if v := os.Getenv(DefaultPodIPEnv); v != "" {
+		ip := net.ParseIP(strings.TrimSpace(v))
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %s=%q is not an ip", ErrInvalidMachineID, DefaultPodIPEnv, v)
+		}
+		return ip, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if n, ok := addr.(*net.IPNet); ok && !n.IP.IsLoopback() && !n.IP.IsLinkLocalUnicast() {
+			return n.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no non-loopback ip found", ErrInvalidMachineID)
+}
+
+// ParseMachineIDSource parses a machine id source from a command line flag:
+//
+//	"3"          StaticMachineID(3)
+//	"env:NODE"   EnvMachineID("NODE")
+//	"hostname"   HostnameOrdinal{Offset: 1}
+//	"ip:8"       IPMachineID{Bits: 8}
+func ParseMachineIDSource(spec string) (MachineIDSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "env":
+		if arg == "" {
+			break
+		}
+		return EnvMachineID(arg), nil
+	case "hostname":
+		return HostnameOrdinal{Offset: 1}, nil
+	case "ip":
+		bits, err := strconv.ParseUint(arg, 10, 8)
+		if err != nil {
+			break
+		}
+		return IPMachineID{Bits: uint8(bits)}, nil
+	default:
+		if mid, err := strconv.ParseInt(spec, 10, 64); err == nil {
+			return StaticMachineID(mid), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown source %q", ErrInvalidMachineID, spec)
+}
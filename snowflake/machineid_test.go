@@ -0,0 +1,82 @@
+package snowflake
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineIDSources(t *testing.T) {
+	t.Setenv("TEST_MACHINE_ID", "7")
+	t.Setenv("TEST_BAD_MACHINE_ID", "seven")
+
+	cases := []struct {
+		src  MachineIDSource
+		want int64
+		err  bool
+	}{
+		{StaticMachineID(3), 3, false},
+		{EnvMachineID("TEST_MACHINE_ID"), 7, false},
+		{EnvMachineID("TEST_BAD_MACHINE_ID"), 0, true},
+		{EnvMachineID("TEST_UNSET_MACHINE_ID"), 0, true},
+		{HostnameOrdinal{Hostname: "idserver-2"}, 2, false},
+		{HostnameOrdinal{Hostname: "idserver-12.idserver.default.svc", Offset: 1}, 13, false},
+		{HostnameOrdinal{Hostname: "idserver"}, 0, true},
+		{HostnameOrdinal{Hostname: "idserver-abc"}, 0, true},
+		{IPMachineID{IP: net.ParseIP("10.0.3.17"), Bits: 8}, 17, false},
+		{IPMachineID{IP: net.ParseIP("10.0.3.17"), Bits: 10}, 3<<8 | 17, false},
+		{IPMachineID{IP: net.ParseIP("fd00::1:2"), Bits: 16}, 2, false},
+		{IPMachineID{IP: net.ParseIP("10.0.3.17")}, 0, true},
+	}
+	for _, c := range cases {
+		mid, err := c.src.MachineID()
+		if c.err {
+			assert.ErrorIs(t, err, ErrInvalidMachineID, "%#v", c.src)
+			continue
+		}
+		assert.NoError(t, err, "%#v", c.src)
+		assert.Equal(t, c.want, mid, "%#v", c.src)
+	}
+}
+
+func TestIPMachineIDFromEnv(t *testing.T) {
+	t.Setenv(DefaultPodIPEnv, "172.16.0.9")
+	mid, err := IPMachineID{Bits: 5}.MachineID()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), mid)
+}
+
+func TestNewMachineIDSource(t *testing.T) {
+	id, err := New(&Options{MachineID: 1, MachineIDSource: HostnameOrdinal{Hostname: "app-4"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), id.machineID)
+
+	// 超出Layout范围时返回错误，而不是使用随机机器ID
+	_, err = New(&Options{MachineIDSource: StaticMachineID(32)})
+	assert.ErrorIs(t, err, ErrInvalidMachineID)
+	_, err = New(&Options{MachineID: -1})
+	assert.ErrorIs(t, err, ErrInvalidMachineID)
+	// 未设置机器ID时不会和其他实例一起使用0
+	_, err = New(&Options{Layout: SnowflakeLayout})
+	assert.ErrorIs(t, err, ErrInvalidMachineID)
+	_, err = New(&Options{MachineIDSource: HostnameOrdinal{Hostname: "app-0"}})
+	assert.ErrorIs(t, err, ErrInvalidMachineID)
+}
+
+func TestParseMachineIDSource(t *testing.T) {
+	for spec, want := range map[string]MachineIDSource{
+		"3":        StaticMachineID(3),
+		"env:NODE": EnvMachineID("NODE"),
+		"hostname": HostnameOrdinal{Offset: 1},
+		"ip:8":     IPMachineID{Bits: 8},
+	} {
+		src, err := ParseMachineIDSource(spec)
+		assert.NoError(t, err)
+		assert.Equal(t, want, src)
+	}
+	for _, spec := range []string{"", "env:", "ip:x", "node"} {
+		_, err := ParseMachineIDSource(spec)
+		assert.ErrorIs(t, err, ErrInvalidMachineID, spec)
+	}
+}
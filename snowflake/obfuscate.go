@@ -0,0 +1,91 @@
+package snowflake
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const feistelRounds = 6
+
+// Obfuscator is a keyed, reversible permutation of IDs, so public APIs can
+// expose IDs which are not enumerable while the database keeps the raw ones.
+//
+// It is a balanced Feistel network with a non-cryptographic round function:
+// enough to hide the order and density of the IDs, not to encrypt them.
+type Obfuscator struct {
+	bits     uint8 // ID的位数
+	halfBits uint8 // Feistel每一半的位数
+	keys     [feistelRounds]uint64
+}
+
+// NewObfuscator creates an Obfuscator over IDs of the given width in bits,
+// usually Layout.Bits(). Encode and Decode reject wider values.
+func NewObfuscator(key []byte, bits uint8) (*Obfuscator, error) {
+	if len(key) == 0 {
+		return nil, errors.New("snowflake: empty obfuscation key")
+	}
+	if bits == 0 || bits > 64 {
+		return nil, fmt.Errorf("snowflake: can not obfuscate %d bits", bits)
+	}
+	o := &Obfuscator{bits: bits, halfBits: (bits + 1) / 2}
+	for i := range o.keys {
+		sum := sha256.Sum256(append([]byte{byte(i)}, key...))
+		o.keys[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return o, nil
+}
+
+// Encode maps a raw ID to its obfuscated form.
+func (o *Obfuscator) Encode(v uint64) (uint64, error) {
+	return o.walk(v, o.encrypt)
+}
+
+// Decode maps an obfuscated ID back to the raw ID.
+func (o *Obfuscator) Decode(v uint64) (uint64, error) {
+	return o.walk(v, o.decrypt)
+}
+
+// walk 对于奇数位数，Feistel在多一位的空间上运行，
+// 结果超出范围时继续置换（cycle walking），直到落回原来的空间。
+func (o *Obfuscator) walk(v uint64, f func(uint64) uint64) (uint64, error) {
+	if o.bits < 64 && v>>o.bits != 0 {
+		return 0, fmt.Errorf("%w: %d is wider than %d bits", ErrInvalidID, v, o.bits)
+	}
+	for {
+		v = f(v)
+		if o.bits == 64 || v>>o.bits == 0 {
+			return v, nil
+		}
+	}
+}
+
+func (o *Obfuscator) encrypt(v uint64) uint64 {
+	mask := uint64(1)<<o.halfBits - 1
+	l, r := v>>o.halfBits&mask, v&mask
+	for _, k := range o.keys {
+		l, r = r, l^(round(r, k)&mask)
+	}
+	return l<<o.halfBits | r
+}
+
+func (o *Obfuscator) decrypt(v uint64) uint64 {
+	mask := uint64(1)<<o.halfBits - 1
+	l, r := v>>o.halfBits&mask, v&mask
+	for i := feistelRounds - 1; i >= 0; i-- {
+		l, r = r^(round(l, o.keys[i])&mask), l
+	}
+	return l<<o.halfBits | r
+}
+
+// round is the splitmix64 finalizer of x^k.
+func round(x, k uint64) uint64 {
+	x ^= k
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscator(t *testing.T) {
+	for _, bits := range []uint8{7, 32, 63, 64} {
+		o, err := NewObfuscator([]byte("studio"), bits)
+		assert.NoError(t, err)
+
+		for i := 0; i < 10000; i++ {
+			v := rand.Uint64()
+			if bits < 64 {
+				v &= 1<<bits - 1
+			}
+			e, err := o.Encode(v)
+			assert.NoError(t, err)
+			if bits < 64 {
+				assert.Zero(t, e>>bits, "%d is wider than %d bits", e, bits)
+			}
+			d, err := o.Decode(e)
+			assert.NoError(t, err)
+			assert.Equal(t, v, d)
+		}
+	}
+}
+
+func TestObfuscator_Permutation(t *testing.T) {
+	o, _ := NewObfuscator([]byte("studio"), 9)
+	seen := make(map[uint64]bool)
+	sequential := 0
+	var last uint64
+	for v := uint64(0); v < 1<<9; v++ {
+		e, err := o.Encode(v)
+		assert.NoError(t, err)
+		assert.False(t, seen[e], "%d encoded twice", e)
+		seen[e] = true
+		if e == last+1 {
+			sequential++
+		}
+		last = e
+	}
+	assert.Less(t, sequential, 10)
+
+	_, err := o.Encode(1 << 9)
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestObfuscator_Key(t *testing.T) {
+	o1, _ := NewObfuscator([]byte("key1"), 32)
+	o2, _ := NewObfuscator([]byte("key2"), 32)
+	id := NewIDGenerator(1)
+	v := uint64(id.NextUID())
+
+	e1, _ := o1.Encode(v)
+	e2, _ := o2.Encode(v)
+	assert.NotEqual(t, e1, e2)
+
+	_, err := NewObfuscator(nil, 32)
+	assert.Error(t, err)
+}
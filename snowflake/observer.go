@@ -0,0 +1,93 @@
+package snowflake
+
+import "time"
+
+// Observer is notified of the events of a generator, e.g. to export metrics.
+// The methods are called while the generator is locked and must be fast.
+type Observer interface {
+	// IDIssued is called after n IDs have been generated.
+	IDIssued(n int)
+	// SequenceExhausted is called when the sequence of a time unit is used up
+	// and the generator had to wait for the next one.
+	SequenceExhausted(wait time.Duration)
+	// ClockRollback is called whenever the clock is found behind the last
+	// timestamp, whatever the rollback policy does about it.
+	ClockRollback(behind time.Duration)
+	// MachineIDFallback is called when NewIDGenerator replaces an invalid
+	// machine id with a random one.
+	MachineIDFallback(requested, actual int64)
+	// LayoutExpiring is called once per generator when the first ID is issued
+	// within Options.ExpiryWarning of the layout's expiry.
+	LayoutExpiring(expiry time.Time)
+}
+
+// DefaultObserver is used by NewIDGenerator and by New when Options.Observer
+// is nil. It must be set before the generators are created.
+var DefaultObserver Observer = NopObserver{}
+
+// NopObserver ignores all events, embed it to implement part of Observer.
+type NopObserver struct{}
+
+func (NopObserver) IDIssued(int)                    {}
+func (NopObserver) SequenceExhausted(time.Duration) {}
+func (NopObserver) ClockRollback(time.Duration)     {}
+func (NopObserver) LayoutExpiring(time.Time)        {}
+func (NopObserver) MachineIDFallback(int64, int64)  {}
+
+// Counter is a monotonic counter, satisfied by prometheus.Counter.
+type Counter interface {
+	Add(float64)
+}
+
+// Histogram samples observations, satisfied by prometheus.Histogram.
+type Histogram interface {
+	Observe(float64)
+}
+
+// MetricsObserver reports the events to Prometheus style counters and
+// histograms, durations are observed in seconds. Nil fields are skipped.
+type MetricsObserver struct {
+	Issued         Counter
+	Exhausted      Counter
+	ExhaustedWait  Histogram
+	Rollbacks      Counter
+	RollbackBehind Histogram
+	Fallbacks      Counter
+	Expiring       Counter // 每个生成器最多加1
+}
+
+func (o *MetricsObserver) IDIssued(n int) {
+	if o.Issued != nil {
+		o.Issued.Add(float64(n))
+	}
+}
+
+func (o *MetricsObserver) SequenceExhausted(wait time.Duration) {
+	if o.Exhausted != nil {
+		o.Exhausted.Add(1)
+	}
+	if o.ExhaustedWait != nil {
+		o.ExhaustedWait.Observe(wait.Seconds())
+	}
+}
+
+func (o *MetricsObserver) ClockRollback(behind time.Duration) {
+	if o.Rollbacks != nil {
+		o.Rollbacks.Add(1)
+	}
+	if o.RollbackBehind != nil {
+		o.RollbackBehind.Observe(behind.Seconds())
+	}
+}
+
+func (o *MetricsObserver) MachineIDFallback(int64, int64) {
+	if o.Fallbacks != nil {
+		o.Fallbacks.Add(1)
+	}
+}
+
+func (o *MetricsObserver) LayoutExpiring(time.Time) {
+	if o.Expiring != nil {
+		o.Expiring.Add(1)
+	}
+}
@@ -0,0 +1,141 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type counter struct {
+	sync.Mutex
+	v float64
+}
+
+func (c *counter) Add(v float64) {
+	c.Lock()
+	defer c.Unlock()
+	c.v += v
+}
+
+func (c *counter) value() float64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.v
+}
+
+type histogram struct {
+	sync.Mutex
+	values []float64
+}
+
+func (h *histogram) Observe(v float64) {
+	h.Lock()
+	defer h.Unlock()
+	h.values = append(h.values, v)
+}
+
+func TestMetricsObserver(t *testing.T) {
+	var issued, exhausted, rollbacks counter
+	var behind histogram
+	clock := NewFakeClock(testStart)
+	id, err := New(&Options{
+		MachineID:         1,
+		Layout:            SnowflakeLayout,
+		RollbackTolerance: time.Second,
+		Clock:             clock,
+		Observer: &MetricsObserver{
+			Issued:         &issued,
+			Exhausted:      &exhausted,
+			Rollbacks:      &rollbacks,
+			RollbackBehind: &behind,
+		},
+	})
+	assert.NoError(t, err)
+
+	// 用完一个时间单位的序列号，第maxSequence+2个ID需要等待下一毫秒
+	for i := int64(0); i < maxSequence+2; i++ {
+		_, err = id.NextID()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(maxSequence+2), issued.value())
+	assert.Equal(t, float64(1), exhausted.value())
+
+	_, err = id.NextN(10)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(maxSequence+12), issued.value())
+
+	clock.Add(-100 * time.Millisecond)
+	_, err = id.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), rollbacks.value())
+	assert.Len(t, behind.values, 1)
+	assert.GreaterOrEqual(t, behind.values[0], 0.1)
+}
+
+func TestObserverAtomic(t *testing.T) {
+	var issued, rollbacks counter
+	clock := NewFakeClock(testStart)
+	id, err := NewAtomic(&Options{
+		MachineID: 1,
+		Layout:    SnowflakeLayout,
+		Rollback:  RollbackError,
+		Clock:     clock,
+		Observer:  &MetricsObserver{Issued: &issued, Rollbacks: &rollbacks},
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = id.NextID()
+		assert.NoError(t, err)
+	}
+	clock.Add(-time.Second)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+	assert.Equal(t, float64(3), issued.value())
+	assert.Equal(t, float64(1), rollbacks.value())
+}
+
+func TestDefaultObserverFallback(t *testing.T) {
+	var fallbacks counter
+	old := DefaultObserver
+	DefaultObserver = &MetricsObserver{Fallbacks: &fallbacks}
+	defer func() { DefaultObserver = old }()
+
+	NewIDGenerator(1)
+	NewIDGenerator(100)
+	assert.Equal(t, float64(1), fallbacks.value())
+}
+
+func TestObserverLayoutExpiring(t *testing.T) {
+	var expiring counter
+	expiry := UID32Layout.Lifetime(testStart).Expiry
+	clock := NewFakeClock(expiry.Add(-400 * 24 * time.Hour))
+	op := func() *Options {
+		return &Options{MachineID: 1, Layout: UID32Layout, Clock: clock, Observer: &MetricsObserver{Expiring: &expiring}}
+	}
+	id, err := New(op())
+	assert.NoError(t, err)
+	aid, err := NewAtomic(op())
+	assert.NoError(t, err)
+
+	_, _ = id.NextID()
+	_, _ = aid.NextID()
+	assert.Equal(t, float64(0), expiring.value())
+
+	// 进入告警期后每个生成器只通知一次
+	clock.Set(expiry.Add(-300 * 24 * time.Hour))
+	for i := 0; i < 3; i++ {
+		_, err = id.NextID()
+		assert.NoError(t, err)
+		_, err = aid.NextID()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(2), expiring.value())
+
+	// 截断的Layout不会到期
+	uid, _ := New(&Options{MachineID: 1, Clock: clock, Observer: &MetricsObserver{Expiring: &expiring}})
+	uid.NextUID()
+	assert.Equal(t, float64(2), expiring.value())
+}
@@ -0,0 +1,106 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Options configures the generator created by New.
+type Options struct {
+	// DatacenterID distinguishes the datacenters or regions, it must be 0 if
+	// the layout has no DatacenterBits.
+	DatacenterID int64
+	// MachineID must be in [1, Layout.MaxMachineID()], 0 is rejected so a
+	// generator without a machine id does not collide with the others.
+	// It must be 0 if the layout has no NodeBits.
+	MachineID int64
+	// MachineIDSource, if set, provides the machine id instead of MachineID,
+	// e.g. HostnameOrdinal for the pods of a StatefulSet.
+	MachineIDSource MachineIDSource
+	Layout          Layout // 默认为UIDLayout
+
+	// Rollback is the policy applied when the clock moves backwards, the
+	// generator fails with ErrClockMovedBackwards once the clock is behind
+	// by more than RollbackTolerance. A negative tolerance means no limit.
+	Rollback          RollbackPolicy
+	RollbackTolerance time.Duration // 默认为DefaultRollbackTolerance
+
+	// ExpiryWarning is how long before a non-truncating layout expires the
+	// Observer is told with LayoutExpiring, a negative value disables it.
+	ExpiryWarning time.Duration // 默认为DefaultExpiryWarning
+
+	// Checkpoint, if set, records a time CheckpointInterval ahead of the
+	// generated IDs. After a restart the generator never issues IDs below it:
+	// New waits for the clock to pass the checkpoint, or applies the rollback
+	// policy if the clock is even further behind.
+	Checkpoint         CheckpointStore
+	CheckpointInterval time.Duration // 默认为DefaultCheckpointInterval
+
+	Clock    Clock    // 默认为SystemClock，测试时可以使用FakeClock
+	Observer Observer // 默认为DefaultObserver
+}
+
+func initConfig(option *Options) error {
+	if option.Layout == (Layout{}) {
+		option.Layout = UIDLayout
+	}
+	if option.Clock == nil {
+		option.Clock = SystemClock
+	}
+	if option.Observer == nil {
+		option.Observer = DefaultObserver
+	}
+	l := option.Layout
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	if lt := l.Lifetime(option.Clock.Now()); !l.Truncate && lt.Remaining == 0 {
+		return fmt.Errorf("%w: layout expired at %s", ErrEpochOverflow, lt.Expiry)
+	}
+	if option.DatacenterID < 0 || option.DatacenterID > l.MaxDatacenterID() {
+		return fmt.Errorf("%w: %d out of range [0, %d]", ErrInvalidDatacenterID, option.DatacenterID, l.MaxDatacenterID())
+	}
+	if option.MachineIDSource != nil {
+		mid, err := option.MachineIDSource.MachineID()
+		if err != nil {
+			return err
+		}
+		option.MachineID = mid
+	}
+	// 没有机器ID位的Layout只能使用0
+	minID := int64(1)
+	if l.MaxMachineID() == 0 {
+		minID = 0
+	}
+	if option.MachineID < minID || option.MachineID > l.MaxMachineID() {
+		return fmt.Errorf("%w: %d out of range [%d, %d]", ErrInvalidMachineID, option.MachineID, minID, l.MaxMachineID())
+	}
+	if option.Checkpoint != nil && l.Truncate {
+		// 截断的时间戳会循环，检查点无法阻止重启后重复
+		return fmt.Errorf("%w: checkpoint needs a non-truncating layout", ErrInvalidLayout)
+	}
+	if option.RollbackTolerance == 0 {
+		option.RollbackTolerance = DefaultRollbackTolerance
+	}
+	if option.ExpiryWarning == 0 {
+		option.ExpiryWarning = DefaultExpiryWarning
+	}
+	return nil
+}
+
+// DefaultExpiryWarning is used when Options.ExpiryWarning is zero.
+const DefaultExpiryWarning = 365 * 24 * time.Hour
+
+// warnAt 返回需要告警的时间戳，截断的Layout或不告警时为math.MaxInt64
+func warnAt(l Layout, warning time.Duration) int64 {
+	if l.Truncate || warning < 0 {
+		return math.MaxInt64
+	}
+	return l.maxTimestamp() - int64((warning+l.TimeUnit-1)/l.TimeUnit) + 1
+}
+
+// expiry 返回非截断Layout的到期时间
+func (l Layout) expiry() time.Time {
+	return l.Lifetime(l.Epoch).Expiry
+}
@@ -0,0 +1,169 @@
+package redisgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+var ErrFallbackRange = errors.New("redisgen: counter reached the ids of the fallback")
+
+// fallbackAfter 回退生成器不会早于Layout起始时间后的一年被使用
+const fallbackAfter = 365 * 24 * time.Hour
+
+// 计数器低于floor时先提升到floor，再预留step个ID，返回预留的最大ID
+var reserveScript = rds.NewScript(`
+local v = tonumber(redis.call("GET", KEYS[1]) or "0")
+local floor = tonumber(ARGV[2])
+if v < floor then
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return redis.call("INCRBY", KEYS[1], ARGV[1])`)
+
+// Generator hands out IDs from a Redis counter, reserving Step IDs with one
+// INCRBY and caching them locally. The IDs are increasing except when the
+// Fallback is used.
+type Generator struct {
+	mu      sync.Mutex
+	options *Options
+
+	floor int64 // 已知使用过的最大ID，Redis丢失数据时计数器从这里恢复
+	next  int64 // 下一个ID
+	end   int64 // 已预留的最大ID
+
+	retryAt time.Time // 回退期间下次重试Redis的时间
+	ceiling int64     // 计数器的上限，回退生成器的ID不会低于它
+}
+
+// New creates a Generator, loading the floor and reserving the first block.
+// It only fails on Redis errors if there is no Fallback.
+func New(ctx context.Context, op *Options) (*Generator, error) {
+	if err := initConfig(op); err != nil {
+		return nil, err
+	}
+	g := &Generator{options: op, ceiling: math.MaxInt64}
+	if op.Fallback != nil {
+		g.ceiling = ceiling(op.Fallback.Layout(), time.Now())
+	}
+	if op.Floor != nil {
+		floor, err := op.Floor(ctx, g.ceiling)
+		if err != nil {
+			return nil, fmt.Errorf("redisgen: load floor: %w", err)
+		}
+		if floor >= g.ceiling {
+			return nil, fmt.Errorf("%w: floor %d", ErrFallbackRange, floor)
+		}
+		g.floor = floor
+	}
+	if err := g.reserve(ctx); err != nil {
+		if op.Fallback == nil || errors.Is(err, ErrFallbackRange) {
+			return nil, err
+		}
+		g.fallback(err)
+	}
+	return g, nil
+}
+
+// NextID returns the next ID.
+func (g *Generator) NextID() (uint64, error) {
+	return g.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID, ctx bounds the Redis call and the Fallback.
+func (g *Generator) NextIDContext(ctx context.Context) (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.next == 0 || g.next > g.end {
+		if time.Now().Before(g.retryAt) {
+			return g.options.Fallback.NextIDContext(ctx)
+		}
+		if err := g.reserve(ctx); err != nil {
+			if g.options.Fallback == nil || errors.Is(err, ErrFallbackRange) {
+				return 0, err
+			}
+			g.fallback(err)
+			return g.options.Fallback.NextIDContext(ctx)
+		}
+	}
+	v := g.next
+	g.next++
+	return uint64(v), nil
+}
+
+// Generate returns NextID in decimal.
+func (g *Generator) Generate() (string, error) {
+	v, err := g.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(v, 10), nil
+}
+
+// reserve 从Redis预留下一段ID，调用时必须持有锁
+func (g *Generator) reserve(ctx context.Context) error {
+	op := g.options
+	ctx, cancel := context.WithTimeout(ctx, op.Timeout)
+	defer cancel()
+	end, err := reserveScript.Run(ctx, op.Rds, []string{op.Key}, op.Step, g.floor).Int64()
+	if err != nil {
+		return err
+	}
+	if end >= g.ceiling {
+		return fmt.Errorf("%w: %d", ErrFallbackRange, end)
+	}
+	if !g.retryAt.IsZero() {
+		op.Log.Infof("redis counter %s is back at %d", op.Key, end)
+		g.retryAt = time.Time{}
+	}
+	g.next, g.end, g.floor = end-op.Step+1, end, end
+	return nil
+}
+
+// fallback 记录Redis不可用，在Retry时间内使用回退生成器
+func (g *Generator) fallback(err error) {
+	if g.retryAt.IsZero() {
+		g.options.Log.Errorf("redis counter %s unavailable, falling back to snowflake: %v", g.options.Key, err)
+	}
+	g.retryAt = time.Now().Add(g.options.Retry)
+}
+
+// ceiling 返回回退生成器在Layout起始时间一年后（或now，取较早者）的第一个ID
+func ceiling(l snowflake.Layout, now time.Time) int64 {
+	since := l.Epoch.Add(fallbackAfter)
+	if now.Before(since) {
+		since = now
+	}
+	ts := int64(since.Sub(l.Epoch) / l.TimeUnit)
+	if shift := l.Bits() - l.TimestampBits; ts >= 1<<(63-shift) {
+		return math.MaxInt64
+	}
+	return ts << (l.Bits() - l.TimestampBits)
+}
+
+// DBFloor returns the largest value of the column in the table, e.g. the
+// primary key of the table the IDs are generated for. The Fallback IDs
+// stored in the column are ignored.
+func DBFloor(db *sqlx.DB, table, column string) FloorFunc {
+	return func(ctx context.Context, below int64) (floor int64, err error) {
+		sqlStr, args := floorSQL(table, column, below).Build()
+		err = db.GetContext(ctx, &floor, sqlStr, args...)
+		return
+	}
+}
+
+func floorSQL(table, column string, below int64) *sqlbuilder.SelectBuilder {
+	sb := sqlbuilder.Select(fmt.Sprintf("COALESCE(MAX(%s), 0)", column)).From(table)
+	sb.Where(sb.LessThan(column, below))
+	return sb
+}
@@ -0,0 +1,160 @@
+package redisgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rds "github.com/go-redis/redis/v8"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+func initOptions(t *testing.T) (*miniredis.Miniredis, *Options) {
+	mr := miniredis.RunT(t)
+	return mr, &Options{
+		Rds:     rds.NewClient(&rds.Options{Addr: mr.Addr()}),
+		Step:    10,
+		Timeout: 100 * time.Millisecond,
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	ctx := context.Background()
+	mr, op := initOptions(t)
+
+	g, err := New(ctx, op)
+	assert.NoError(t, err)
+	for i := uint64(1); i <= 25; i++ {
+		v, err := g.NextID()
+		assert.NoError(t, err)
+		assert.Equal(t, i, v)
+	}
+	// 预留了3段ID
+	n, _ := mr.Get(op.Key)
+	assert.Equal(t, "30", n)
+
+	// 多个实例共享计数器
+	g2, err := New(ctx, &Options{Rds: op.Rds, Step: 10})
+	assert.NoError(t, err)
+	v, err := g2.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(31), v)
+}
+
+func TestGenerator_Floor(t *testing.T) {
+	ctx := context.Background()
+	mr, op := initOptions(t)
+	op.Floor = func(context.Context, int64) (int64, error) { return 100, nil }
+
+	g, err := New(ctx, op)
+	assert.NoError(t, err)
+	v, _ := g.NextID()
+	assert.Equal(t, uint64(101), v)
+
+	// Redis丢失数据后从本地记录的最大ID恢复
+	mr.FlushAll()
+	for i := 0; i < 10; i++ {
+		v, err = g.NextID()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(111), v)
+
+	op.Floor = func(context.Context, int64) (int64, error) { return 0, errors.New("db down") }
+	_, err = New(ctx, op)
+	assert.Error(t, err)
+}
+
+func TestGenerator_Fallback(t *testing.T) {
+	ctx := context.Background()
+	mr, op := initOptions(t)
+	op.Retry = 50 * time.Millisecond
+
+	// 没有回退生成器时返回错误
+	mr.Close()
+	_, err := New(ctx, op)
+	assert.Error(t, err)
+
+	fallback, err := snowflake.New(&snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout})
+	assert.NoError(t, err)
+	op.Fallback = fallback
+	g, err := New(ctx, op)
+	assert.NoError(t, err)
+	v, err := g.NextID()
+	assert.NoError(t, err)
+	p, err := fallback.Decompose(v)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), p.Time, time.Second)
+
+	// Redis恢复后重新使用计数器
+	assert.NoError(t, mr.Restart())
+	time.Sleep(60 * time.Millisecond)
+	v, err = g.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), v)
+}
+
+func TestGenerator_FallbackRange(t *testing.T) {
+	ctx := context.Background()
+	_, op := initOptions(t)
+	fallback, _ := snowflake.New(&snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout})
+	op.Fallback = fallback
+	op.Floor = func(context.Context, int64) (int64, error) { return 1 << 62, nil }
+
+	_, err := New(ctx, op)
+	assert.ErrorIs(t, err, ErrFallbackRange)
+
+	op.Floor = nil
+	op.Fallback, _ = snowflake.New(&snowflake.Options{MachineID: 1})
+	_, err = New(ctx, op)
+	assert.Error(t, err)
+}
+
+func TestGenerator_FallbackFloor(t *testing.T) {
+	ctx := context.Background()
+	mr, op := initOptions(t)
+	op.Step, op.Retry = 1, time.Hour
+	op.Fallback, _ = snowflake.New(&snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout})
+	// 模拟同时保存计数器ID和回退ID的列
+	var stored []int64
+	op.Floor = func(_ context.Context, below int64) (floor int64, _ error) {
+		for _, v := range stored {
+			if v < below && v > floor {
+				floor = v
+			}
+		}
+		return floor, nil
+	}
+
+	g, err := New(ctx, op)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		v, _ := g.NextID()
+		stored = append(stored, int64(v))
+	}
+	mr.Close()
+	v, err := g.NextID()
+	assert.NoError(t, err)
+	assert.Greater(t, v, uint64(1<<40))
+	stored = append(stored, int64(v))
+
+	// Redis丢失数据后重启，Floor忽略回退ID，计数器从已使用的最大计数器ID恢复
+	assert.NoError(t, mr.Restart())
+	mr.FlushAll()
+	g, err = New(ctx, op)
+	assert.NoError(t, err)
+	v, err = g.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), v)
+}
+
+func TestDBFloor_SQL(t *testing.T) {
+	sqlStr, args := floorSQL("orders", "id", 1<<40).Build()
+	s, err := sqlbuilder.MySQL.Interpolate(sqlStr, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT COALESCE(MAX(id), 0) FROM orders WHERE id < 1099511627776", s)
+}
@@ -0,0 +1,72 @@
+package redisgen
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+type Log interface {
+	Infof(format string, a ...any)
+	Error(a ...any)
+	Errorf(format string, args ...any)
+}
+
+// FloorFunc returns the largest ID already in use, e.g. DBFloor. IDs at or
+// above below are the Fallback's and must be ignored, so the floor can be
+// read from a column which also stores the Fallback IDs.
+type FloorFunc func(ctx context.Context, below int64) (int64, error)
+
+type Options struct {
+	Rds  *rds.Client
+	Key  string // 计数器的key，默认为"studio.id.counter"
+	Step int64  // 每次从Redis预留的ID数量，默认为1000
+
+	// Floor is loaded on startup, the counter is raised to it before every
+	// reservation so IDs are not reused after Redis loses its data.
+	Floor FloorFunc
+
+	// Fallback generates the IDs while Redis is unreachable, nil to fail
+	// instead. Its layout must not truncate, the counter must stay below its
+	// IDs to avoid collisions: below the first Fallback ID one year after the
+	// layout's epoch, or of the current time if that is earlier.
+	Fallback *snowflake.ID
+	Retry    time.Duration // 回退后重试Redis的间隔，默认为1s
+	Timeout  time.Duration // 访问Redis的超时时间，默认为1s
+	Log      Log
+}
+
+func initConfig(option *Options) error {
+	if option.Rds == nil {
+		return errors.New("invalid redis client")
+	}
+	if option.Fallback != nil && option.Fallback.Layout().Truncate {
+		return errors.New("invalid fallback layout: truncating")
+	}
+	if option.Key == "" {
+		option.Key = "studio.id.counter"
+	}
+	if option.Step <= 0 {
+		option.Step = 1000
+	}
+	if option.Retry <= 0 {
+		option.Retry = time.Second
+	}
+	if option.Timeout <= 0 {
+		option.Timeout = time.Second
+	}
+	if option.Log == nil {
+		option.Log = nopLog{}
+	}
+	return nil
+}
+
+type nopLog struct{}
+
+func (nopLog) Infof(string, ...any)  {}
+func (nopLog) Error(...any)          {}
+func (nopLog) Errorf(string, ...any) {}
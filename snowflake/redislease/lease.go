@@ -0,0 +1,138 @@
+package redislease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+)
+
+var (
+	ErrPoolExhausted = errors.New("redislease: no free machine id")
+	ErrLeaseLost     = errors.New("redislease: lease lost")
+)
+
+// 只有持有者才能续约和释放
+var (
+	renewScript = rds.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = rds.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Lease is a machine id leased from redis. It is renewed in the background
+// until Release is called.
+type Lease struct {
+	options   *LeaseOptions
+	machineID int64
+	key       string
+
+	acquired time.Time // 获取租约前的时间，用于计算key的过期时间
+
+	once  sync.Once
+	close chan struct{}
+	done  chan struct{}
+	lost  chan struct{}
+}
+
+// Acquire leases a machine id in [MinID, MaxID] which no other instance holds,
+// it fails with ErrPoolExhausted if every id is taken.
+func Acquire(ctx context.Context, op *LeaseOptions) (*Lease, error) {
+	if err := initConfig(op); err != nil {
+		return nil, err
+	}
+
+	// 从随机位置开始尝试，减少多个实例同时启动时的冲突
+	size := op.MaxID - op.MinID + 1
+	start := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(size)
+	for i := int64(0); i < size; i++ {
+		mid := op.MinID + (start+i)%size
+		key := fmt.Sprintf("%s:%d", op.Prefix, mid)
+		acquired := time.Now()
+		ok, err := op.Rds.SetNX(ctx, key, op.Owner, op.TTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		l := &Lease{
+			options:   op,
+			machineID: mid,
+			key:       key,
+			acquired:  acquired,
+			close:     make(chan struct{}),
+			done:      make(chan struct{}),
+			lost:      make(chan struct{}),
+		}
+		op.Log.Infof("leased machine id %d as %s", mid, op.Owner)
+		go l.renew()
+		return l, nil
+	}
+	return nil, fmt.Errorf("%w in [%d, %d]", ErrPoolExhausted, op.MinID, op.MaxID)
+}
+
+// MachineID returns the leased machine id.
+func (l *Lease) MachineID() int64 {
+	return l.machineID
+}
+
+// Lost is closed when the lease can no longer be renewed, e.g. redis has
+// been unreachable for TTL-Renew. It is closed before the key expires in
+// redis, the machine id must not be used after that.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lease and frees the machine id.
+func (l *Lease) Release(ctx context.Context) error {
+	l.once.Do(func() { close(l.close) })
+	<-l.done
+	return releaseScript.Run(ctx, l.options.Rds, []string{l.key}, l.options.Owner).Err()
+}
+
+func (l *Lease) renew() {
+	var (
+		op     = l.options
+		ticker = time.NewTicker(op.Renew)
+		// key最晚的过期时间：上次成功续约开始前的时间+TTL
+		expiry = l.acquired.Add(op.TTL)
+	)
+	defer ticker.Stop()
+	defer close(l.done)
+
+	for {
+		select {
+		case <-l.close:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), op.Renew)
+		n, err := renewScript.Run(ctx, op.Rds, []string{l.key}, op.Owner, op.TTL.Milliseconds()).Int64()
+		cancel()
+		switch {
+		// 下次续约前key可能已经过期，提前放弃租约，避免其他实例占用后仍在使用
+		case err != nil && time.Until(expiry) > op.Renew:
+			op.Log.Errorf("renew machine id %d err: %v", l.machineID, err)
+		case err != nil || n == 0:
+			op.Log.Errorf("machine id %d: %v", l.machineID, ErrLeaseLost)
+			close(l.lost)
+			return
+		default:
+			expiry = start.Add(op.TTL)
+		}
+	}
+}
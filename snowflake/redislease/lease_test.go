@@ -0,0 +1,107 @@
+package redislease
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rds "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func initLeaseOptions(t *testing.T) (*miniredis.Miniredis, func() *LeaseOptions) {
+	mr := miniredis.RunT(t)
+	client := rds.NewClient(&rds.Options{Addr: mr.Addr()})
+	return mr, func() *LeaseOptions {
+		return &LeaseOptions{
+			Rds:   client,
+			MinID: 1,
+			MaxID: 3,
+			TTL:   300 * time.Millisecond,
+		}
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	_, newOptions := initLeaseOptions(t)
+
+	seen := make(map[int64]bool)
+	var leases []*Lease
+	for i := 0; i < 3; i++ {
+		l, err := Acquire(ctx, newOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, seen[l.MachineID()], "machine id %d leased twice", l.MachineID())
+		assert.True(t, l.MachineID() >= 1 && l.MachineID() <= 3)
+		seen[l.MachineID()] = true
+		leases = append(leases, l)
+	}
+
+	_, err := Acquire(ctx, newOptions())
+	assert.ErrorIs(t, err, ErrPoolExhausted)
+
+	// 续约后租约仍然有效
+	time.Sleep(500 * time.Millisecond)
+	_, err = Acquire(ctx, newOptions())
+	assert.ErrorIs(t, err, ErrPoolExhausted)
+
+	assert.NoError(t, leases[1].Release(ctx))
+	l, err := Acquire(ctx, newOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, leases[1].MachineID(), l.MachineID())
+
+	for _, l := range append(leases, l) {
+		assert.NoError(t, l.Release(ctx))
+	}
+}
+
+func TestLease_Lost(t *testing.T) {
+	ctx := context.Background()
+	mr, newOptions := initLeaseOptions(t)
+
+	l, err := Acquire(ctx, newOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟租约过期后被其他实例占用
+	mr.Set(l.key, "other")
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease should be lost")
+	}
+	assert.NoError(t, l.Release(ctx))
+	assert.Equal(t, "other", must(mr.Get(l.key)))
+}
+
+func must(s string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestLease_LostBeforeExpiry(t *testing.T) {
+	ctx := context.Background()
+	mr, newOptions := initLeaseOptions(t)
+	op := newOptions()
+
+	start := time.Now()
+	l, err := Acquire(ctx, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Redis不可用时，租约必须在key过期前失效
+	mr.Close()
+
+	select {
+	case <-l.Lost():
+		assert.Less(t, time.Since(start), op.TTL)
+	case <-time.After(op.TTL - time.Since(start)):
+		t.Fatal("lease should be lost before the key expires")
+	}
+}
@@ -0,0 +1,60 @@
+package redislease
+
+import (
+	"errors"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+type Log interface {
+	Infof(format string, a ...any)
+	Error(a ...any)
+	Errorf(format string, args ...any)
+}
+
+type LeaseOptions struct {
+	Rds    *rds.Client
+	Prefix string // key前缀，默认为"studio.snowflake.machine"
+	MinID  int64  // 默认为1，机器ID 0 不可用
+	MaxID  int64  // 一般为Layout.MaxMachineID()
+	TTL    time.Duration
+	Renew  time.Duration // 续约间隔，默认为TTL/3
+	Owner  string
+	Log    Log
+}
+
+func initConfig(option *LeaseOptions) error {
+	if option.Rds == nil {
+		return errors.New("invalid redis client")
+	}
+	if option.MinID == 0 {
+		option.MinID = 1
+	}
+	if option.MinID < 1 || option.MaxID < option.MinID {
+		return errors.New("invalid machine id range")
+	}
+	if option.Prefix == "" {
+		option.Prefix = "studio.snowflake.machine"
+	}
+	if option.TTL <= 0 {
+		option.TTL = 30 * time.Second
+	}
+	if option.Renew <= 0 || option.Renew >= option.TTL {
+		option.Renew = option.TTL / 3
+	}
+	if option.Owner == "" {
+		option.Owner = uuid.New().String()
+	}
+	if option.Log == nil {
+		option.Log = nopLog{}
+	}
+	return nil
+}
+
+type nopLog struct{}
+
+func (nopLog) Infof(string, ...any)  {}
+func (nopLog) Error(...any)          {}
+func (nopLog) Errorf(string, ...any) {}
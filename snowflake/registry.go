@@ -0,0 +1,87 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownGenerator = errors.New("snowflake: unknown generator")
+
+// IDGenerator is a generator of uint64 IDs which can be registered, e.g. ID,
+// AtomicID and EIDGenerator.
+type IDGenerator interface {
+	NextIDContext(ctx context.Context) (uint64, error)
+}
+
+// Registry holds named generators, e.g. "user", "enterprise" and "order",
+// each with its own layout and sequence so they do not compete for the same
+// sequence per time unit. It is safe for concurrent use.
+type Registry struct {
+	mu   sync.RWMutex
+	gens map[string]IDGenerator
+}
+
+func NewRegistry() *Registry {
+	return &Registry{gens: make(map[string]IDGenerator)}
+}
+
+// Register creates a generator with the options under name.
+func (r *Registry) Register(name string, op *Options) (*ID, error) {
+	id, err := New(op)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.Add(name, id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Add registers g under name, e.g. an AtomicID for a hot path or an
+// EIDGenerator for enterprise IDs.
+func (r *Registry) Add(name string, g IDGenerator) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exist := r.gens[name]; exist {
+		return fmt.Errorf("snowflake: generator %q already registered", name)
+	}
+	r.gens[name] = g
+	return nil
+}
+
+// Get returns the generator registered under name.
+func (r *Registry) Get(name string) (IDGenerator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.gens[name]
+	return id, ok
+}
+
+// NextID returns the next ID of the generator registered under name.
+func (r *Registry) NextID(name string) (uint64, error) {
+	return r.NextIDContext(context.Background(), name)
+}
+
+// NextIDContext is like NextID but honors ctx like the generator's NextIDContext.
+func (r *Registry) NextIDContext(ctx context.Context, name string) (uint64, error) {
+	id, ok := r.Get(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownGenerator, name)
+	}
+	return id.NextIDContext(ctx)
+}
+
+// Names returns the sorted names of the registered generators.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.gens))
+	for name := range r.gens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,73 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	c := NewFakeClock(testStart)
+	r := NewRegistry()
+	user, err := r.Register("user", &Options{MachineID: 1, Layout: UID32Layout, Clock: c})
+	assert.NoError(t, err)
+	order, err := r.Register("order", &Options{MachineID: 1, Layout: SnowflakeLayout, Clock: c})
+	assert.NoError(t, err)
+
+	_, err = r.Register("user", &Options{MachineID: 2})
+	assert.Error(t, err)
+	_, err = r.Register("bad", &Options{MachineID: -1})
+	assert.Error(t, err)
+
+	got, ok := r.Get("user")
+	assert.True(t, ok)
+	assert.Same(t, user, got)
+	assert.Equal(t, []string{"order", "user"}, r.Names())
+
+	// 每个生成器有独立的序列号
+	for i := 0; i < 10; i++ {
+		_, err = r.NextID("order")
+		assert.NoError(t, err)
+	}
+	v, err := r.NextID("user")
+	assert.NoError(t, err)
+	p, _ := user.Decompose(v)
+	assert.Zero(t, p.Sequence)
+	assert.Equal(t, int64(9), order.sequence)
+
+	_, err = r.NextID("enterprise")
+	assert.ErrorIs(t, err, ErrUnknownGenerator)
+
+	// 也可以注册其他生成器
+	eid, err := NewEIDGenerator(&EIDOptions{Options: Options{MachineID: 1, Clock: c}})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Add("enterprise", eid))
+	assert.Error(t, r.Add("user", eid))
+	v, err = r.NextID("enterprise")
+	assert.NoError(t, err)
+	assert.NoError(t, eid.Validate(v))
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry()
+	names := []string{"user", "enterprise", "order"}
+	wg := sync.WaitGroup{}
+	for _, name := range names {
+		_, err := r.Register(name, &Options{MachineID: 1, Layout: SnowflakeLayout})
+		assert.NoError(t, err)
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					if _, err := r.NextID(name); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(name)
+		}
+	}
+	wg.Wait()
+}
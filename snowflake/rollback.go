@@ -0,0 +1,71 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrClockMovedBackwards = errors.New("snowflake: clock moved backwards")
+
+// DefaultRollbackTolerance is used when Options.RollbackTolerance is zero.
+const DefaultRollbackTolerance = 10 * time.Millisecond
+
+// RollbackPolicy decides what the generator does when the clock moves backwards,
+// e.g. after an NTP adjustment.
+type RollbackPolicy int
+
+const (
+	// RollbackWait blocks until the clock catches up with the last timestamp.
+	RollbackWait RollbackPolicy = iota
+	// RollbackBorrow keeps issuing IDs from the last timestamp, moving it
+	// forward on its own when the sequence is exhausted.
+	RollbackBorrow
+	// RollbackError fails with ErrClockMovedBackwards.
+	RollbackError
+)
+
+func (p RollbackPolicy) String() string {
+	switch p {
+	case RollbackWait:
+		return "wait"
+	case RollbackBorrow:
+		return "borrow"
+	case RollbackError:
+		return "error"
+	}
+	return fmt.Sprintf("RollbackPolicy(%d)", int(p))
+}
+
+// tick returns the timestamp for the next ID according to the rollback policy.
+// borrowed reports that the returned timestamp is ahead of the clock.
+// borrow forces RollbackBorrow without tolerance. Under RollbackWait it
+// releases the lock while sleeping, so it must be called with the lock held.
+func (id *ID) tick(ctx context.Context, borrow bool) (now int64, borrowed bool, err error) {
+	for observed := false; ; observed = true {
+		now = id.layout.timestamp(id.clock.Now())
+		if now >= id.timestamp {
+			return now, false, nil
+		}
+
+		behind := time.Duration(id.timestamp-now) * id.layout.TimeUnit
+		if !observed {
+			id.observer.ClockRollback(behind)
+		}
+		if borrow {
+			return id.timestamp, true, nil
+		}
+		cause := fmt.Errorf("%w by %s", ErrClockMovedBackwards, behind)
+		if id.rollback == RollbackError || (id.tolerance >= 0 && behind > id.tolerance) {
+			return 0, false, cause
+		}
+		if id.rollback == RollbackBorrow {
+			return id.timestamp, true, nil
+		}
+		// 醒来后其他调用可能已经推进了时间戳，重新检查
+		if err = id.sleep(ctx, id.timestamp, cause); err != nil {
+			return 0, false, err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rollback moves the last timestamp of id ahead of the clock by d.
+func rollback(id *ID, d time.Duration) int64 {
+	id.timestamp = id.layout.timestamp(time.Now().Add(d))
+	return id.timestamp
+}
+
+func TestRollbackError(t *testing.T) {
+	id, _ := New(&Options{MachineID: 1, Rollback: RollbackError})
+	rollback(id, time.Second)
+
+	_, err := id.TryNextUID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
+
+func TestNextUID_Rollback(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	id, err := New(&Options{MachineID: 1, Clock: clock})
+	assert.NoError(t, err)
+	uids := map[uint32]bool{id.NextUID(): true}
+
+	// 默认策略下回拨超过容忍度时NextUID仍然借用上次的时间戳
+	clock.Add(-20 * time.Millisecond)
+	_, err = id.TryNextUID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+	for i := int64(0); i < 2*(maxSequence+1); i++ {
+		uid := id.NextUID()
+		assert.False(t, uids[uid], "generated the repeated uid: %d", uid)
+		uids[uid] = true
+	}
+}
+
+func TestRollbackWait(t *testing.T) {
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout, RollbackTolerance: time.Second})
+	last := rollback(id, 50*time.Millisecond)
+
+	start := time.Now()
+	v, err := id.NextID()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	p, _ := id.Decompose(v)
+	assert.GreaterOrEqual(t, p.Timestamp, last)
+
+	rollback(id, 2*time.Second)
+	_, err = id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
+
+func TestRollbackBorrow(t *testing.T) {
+	id, _ := New(&Options{
+		MachineID:         1,
+		Layout:            SnowflakeLayout,
+		Rollback:          RollbackBorrow,
+		RollbackTolerance: time.Minute,
+	})
+	last := rollback(id, time.Second)
+
+	ids := make(map[uint64]struct{})
+	for i := int64(0); i < 2*(maxSequence+1); i++ {
+		v, err := id.NextID()
+		assert.NoError(t, err)
+		if _, exist := ids[v]; exist {
+			t.Fatalf("generated the %dth repeated id: %d", i, v)
+		}
+		ids[v] = struct{}{}
+	}
+	// 序列号用尽后向后借用时间戳
+	assert.Equal(t, last+2, id.timestamp)
+
+	rollback(id, 2*time.Minute)
+	_, err := id.NextID()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
+
+func TestNewIDGenerator_Rollback(t *testing.T) {
+	id := NewIDGenerator(1)
+	last := rollback(id, time.Hour)
+
+	assert.NotPanics(t, func() { id.NextUID() })
+	assert.Equal(t, last, id.timestamp)
+}
@@ -0,0 +1,149 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	Store Store
+	Tag   string
+	// 当前号段剩余比例低于Prefetch时在后台预取下一个号段，默认为0.2
+	Prefetch float64
+	Timeout  time.Duration // 获取号段的超时时间，默认为3s
+}
+
+func initConfig(option *Options) error {
+	if option.Store == nil {
+		return errors.New("invalid segment store")
+	}
+	if option.Tag == "" {
+		return errors.New("invalid biz tag")
+	}
+	if option.Prefetch <= 0 || option.Prefetch >= 1 {
+		option.Prefetch = 0.2
+	}
+	if option.Timeout <= 0 {
+		option.Timeout = 3 * time.Second
+	}
+	return nil
+}
+
+// Allocator hands out strictly increasing IDs from segments reserved in the
+// store. It keeps two buffers: the segment in use and the next one, which is
+// fetched in the background before the current one runs out.
+type Allocator struct {
+	mu      sync.Mutex
+	options *Options
+
+	cur     Segment
+	cursor  uint64        // 下一个ID
+	next    *Segment      // 预取的号段
+	loading chan struct{} // 预取中时不为空，预取完成后关闭
+	err     error         // 最近一次预取的错误
+}
+
+// New creates an Allocator and reserves its first segment.
+func New(ctx context.Context, op *Options) (*Allocator, error) {
+	if err := initConfig(op); err != nil {
+		return nil, err
+	}
+	seg, err := op.Store.NextSegment(ctx, op.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if err = validate(op.Tag, seg, 0); err != nil {
+		return nil, err
+	}
+	return &Allocator{options: op, cur: seg, cursor: seg.Start}, nil
+}
+
+// NextID returns the next ID, it only blocks when both buffers are used up.
+func (a *Allocator) NextID() (uint64, error) {
+	return a.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID but gives up waiting for the next segment
+// once ctx is done. The segment is still loaded in the background.
+func (a *Allocator) NextIDContext(ctx context.Context) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		if a.cursor < a.cur.End {
+			v := a.cursor
+			a.cursor++
+			if a.next == nil && a.loading == nil &&
+				float64(a.cur.End-a.cursor) < a.options.Prefetch*float64(a.cur.End-a.cur.Start) {
+				a.load()
+			}
+			return v, nil
+		}
+
+		switch {
+		case a.next != nil:
+			a.cur, a.cursor, a.next = *a.next, a.next.Start, nil
+		case a.loading != nil:
+			loading := a.loading
+			a.mu.Unlock()
+			select {
+			case <-loading:
+				a.mu.Lock()
+			case <-ctx.Done():
+				a.mu.Lock()
+				return 0, fmt.Errorf("segment: waiting for %s: %w", a.options.Tag, ctx.Err())
+			}
+		case a.err != nil:
+			err := a.err
+			a.err = nil
+			return 0, err
+		default:
+			a.load()
+		}
+	}
+}
+
+// Generate returns NextID in decimal.
+func (a *Allocator) Generate() (string, error) {
+	v, err := a.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(v, 10), nil
+}
+
+// load 在后台获取下一个号段，调用时必须持有锁
+func (a *Allocator) load() {
+	loading := make(chan struct{})
+	a.loading = loading
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), a.options.Timeout)
+		seg, err := a.options.Store.NextSegment(ctx, a.options.Tag)
+		cancel()
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.loading = nil
+		if err == nil {
+			err = validate(a.options.Tag, seg, a.cur.End)
+		}
+		if err != nil {
+			a.err = err
+		} else {
+			a.next, a.err = &seg, nil
+		}
+		close(loading)
+	}()
+}
+
+// validate 拒绝空号段和低于已分配号段的号段，否则会生成重复的ID
+func validate(tag string, seg Segment, last uint64) error {
+	if seg.Start >= seg.End || seg.Start < last {
+		return fmt.Errorf("%w: %s got [%d, %d) after %d", ErrInvalidSegment, tag, seg.Start, seg.End, last)
+	}
+	return nil
+}
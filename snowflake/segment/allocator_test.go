@@ -0,0 +1,181 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memStore struct {
+	sync.Mutex
+	maxID uint64
+	step  uint64
+	calls int
+	delay time.Duration
+	err   error
+}
+
+func (s *memStore) NextSegment(_ context.Context, _ string) (Segment, error) {
+	time.Sleep(s.delay)
+	s.Lock()
+	defer s.Unlock()
+	s.calls++
+	if s.err != nil {
+		return Segment{}, s.err
+	}
+	s.maxID += s.step
+	return Segment{Start: s.maxID - s.step + 1, End: s.maxID + 1}, nil
+}
+
+func (s *memStore) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.calls
+}
+
+func TestAllocator(t *testing.T) {
+	store := &memStore{step: 100}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.NoError(t, err)
+
+	var last uint64
+	for i := 0; i < 1000; i++ {
+		v, err := a.NextID()
+		assert.NoError(t, err)
+		assert.Equal(t, last+1, v)
+		last = v
+	}
+	assert.GreaterOrEqual(t, store.count(), 10)
+}
+
+func TestAllocator_Prefetch(t *testing.T) {
+	store := &memStore{step: 10, delay: 10 * time.Millisecond}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order", Prefetch: 0.5})
+	assert.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		_, _ = a.NextID()
+	}
+	// 剩余不足一半时开始预取
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, store.count())
+	a.mu.Lock()
+	assert.NotNil(t, a.next)
+	a.mu.Unlock()
+}
+
+func TestAllocator_Concurrent(t *testing.T) {
+	store := &memStore{step: 50, delay: time.Millisecond}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.NoError(t, err)
+
+	ids := make(chan uint64, 8*1000)
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				v, err := a.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ids <- v
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[uint64]bool)
+	for v := range ids {
+		assert.False(t, seen[v], "generated the repeated id: %d", v)
+		seen[v] = true
+	}
+	assert.Len(t, seen, 8000)
+}
+
+func TestAllocator_Error(t *testing.T) {
+	store := &memStore{step: 2}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.NoError(t, err)
+
+	store.Lock()
+	store.err = errors.New("db down")
+	store.Unlock()
+	_, _ = a.NextID()
+	_, _ = a.NextID()
+	_, err = a.NextID()
+	assert.ErrorIs(t, err, store.err)
+
+	store.Lock()
+	store.err = nil
+	store.Unlock()
+	v, err := a.NextID()
+	assert.NoError(t, err)
+	assert.Greater(t, v, uint64(2))
+
+	_, err = New(context.Background(), &Options{Store: store})
+	assert.Error(t, err)
+}
+
+func TestAllocator_NextIDContext(t *testing.T) {
+	store := &memStore{step: 2, delay: 100 * time.Millisecond}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.NoError(t, err)
+	var last uint64
+	for i := 0; i < 2; i++ {
+		last, err = a.NextID()
+		assert.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = a.NextIDContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 号段仍在后台加载，之后可以继续获取
+	v, err := a.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, last+1, v)
+}
+
+func TestAllocator_InvalidSegment(t *testing.T) {
+	store := &memStore{step: 2}
+	a, err := New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.NoError(t, err)
+	_, _ = a.NextID()
+	_, _ = a.NextID()
+
+	// 空号段
+	store.Lock()
+	store.step = 0
+	store.Unlock()
+	_, err = a.NextID()
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+
+	// 号段倒退
+	store.Lock()
+	store.maxID, store.step = 0, 2
+	store.Unlock()
+	_, err = a.NextID()
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+
+	store.Lock()
+	store.maxID = 10
+	store.Unlock()
+	v, err := a.NextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), v)
+
+	store.Lock()
+	store.step = 0
+	store.Unlock()
+	_, err = New(context.Background(), &Options{Store: store, Tag: "order"})
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+}
@@ -0,0 +1,104 @@
+package segment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUnknownTag     = errors.New("segment: unknown biz tag")
+	ErrInvalidSegment = errors.New("segment: invalid segment")
+)
+
+// Segment is a range of IDs [Start, End) reserved for one allocator.
+type Segment struct {
+	Start uint64
+	End   uint64
+}
+
+// Store reserves segments, every segment of a tag must be above the previous ones.
+type Store interface {
+	NextSegment(ctx context.Context, tag string) (Segment, error)
+}
+
+const idSegmentTable = "id_segments"
+
+type IDSegment struct {
+	BizTag string `db:"biz_tag"`
+	MaxID  int64  `db:"max_id"` // 已分配的最大ID
+	Step   int64  `db:"step"`   // 每次分配的号段长度
+}
+
+var idSegmentStruct = sqlbuilder.NewStruct(new(IDSegment))
+
+// DBStore reserves segments from the `id_segments` table.
+type DBStore struct {
+	db *sqlx.DB
+}
+
+func NewDBStore(db *sqlx.DB) *DBStore {
+	return &DBStore{db}
+}
+
+// NextSegment locks the row of the tag, moves its max_id forward by step in
+// a transaction and returns the range in between.
+func (s *DBStore) NextSegment(ctx context.Context, tag string) (seg Segment, err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// 先读取并锁定该行，step为0时UPDATE不会修改任何行，无法与不存在的tag区分
+	sqlStr, args := selectSQL(tag).Build()
+	var row IDSegment
+	if err = tx.GetContext(ctx, &row, sqlStr, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("%w: %s", ErrUnknownTag, tag)
+		}
+		return
+	}
+	if seg, err = nextSegment(row); err != nil {
+		return
+	}
+
+	sqlStr, args = updateSQL(tag).Build()
+	if _, err = tx.ExecContext(ctx, sqlStr, args...); err != nil {
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	return seg, nil
+}
+
+func selectSQL(tag string) *sqlbuilder.SelectBuilder {
+	sb := idSegmentStruct.SelectFrom(idSegmentTable)
+	sb.Where(sb.E("biz_tag", tag))
+	return sb.ForUpdate()
+}
+
+func updateSQL(tag string) *sqlbuilder.UpdateBuilder {
+	ub := sqlbuilder.Update(idSegmentTable)
+	ub.Set("max_id = max_id + step")
+	ub.Where(ub.E("biz_tag", tag))
+	return ub
+}
+
+// nextSegment 返回row之后的号段，step不为正时号段为空或倒退，不能提交
+func nextSegment(row IDSegment) (Segment, error) {
+	if row.Step <= 0 || row.MaxID < 0 {
+		return Segment{}, fmt.Errorf("%w: %s has max_id %d and step %d", ErrInvalidSegment, row.BizTag, row.MaxID, row.Step)
+	}
+	maxID := row.MaxID + row.Step
+	return Segment{Start: uint64(row.MaxID + 1), End: uint64(maxID + 1)}, nil
+}
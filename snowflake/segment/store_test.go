@@ -0,0 +1,37 @@
+package segment
+
+import (
+	"testing"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBStore_SQL(t *testing.T) {
+	for _, tc := range []struct {
+		b    sqlbuilder.Builder
+		want string
+	}{
+		{selectSQL("order"), "SELECT id_segments.biz_tag, id_segments.max_id, id_segments.step FROM id_segments WHERE biz_tag = 'order' FOR UPDATE"},
+		{updateSQL("order"), "UPDATE id_segments SET max_id = max_id + step WHERE biz_tag = 'order'"},
+	} {
+		sqlStr, args := tc.b.Build()
+		s, err := sqlbuilder.MySQL.Interpolate(sqlStr, args)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, s)
+	}
+}
+
+func TestDBStore_NextSegment(t *testing.T) {
+	seg, err := nextSegment(IDSegment{BizTag: "order", MaxID: 1000, Step: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, Segment{Start: 1001, End: 1101}, seg)
+
+	// step为0时返回ErrInvalidSegment，而不是ErrUnknownTag
+	_, err = nextSegment(IDSegment{BizTag: "order", MaxID: 1000})
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+	_, err = nextSegment(IDSegment{BizTag: "order", MaxID: 1000, Step: -1})
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+	_, err = nextSegment(IDSegment{BizTag: "order", MaxID: -1, Step: 100})
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+}
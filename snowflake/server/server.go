@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+// DefaultName is the generator used when a request does not name one.
+const DefaultName = "default"
+
+// MaxBatch is the largest number of IDs returned by one batch request.
+const MaxBatch = 10000
+
+// ErrStopped is returned instead of new IDs once the server is stopped.
+var ErrStopped = errors.New("server: stopped issuing ids")
+
+// Server exposes the generators of a registry over HTTP/JSON, both as
+// REST style routes and as gRPC style POST routes:
+//
+//	GET  /v1/next?name=user                 POST /snowflake.v1.IDService/Next   {"name":"user"}
+//	GET  /v1/batch?name=user&n=100          POST /snowflake.v1.IDService/Batch  {"name":"user","n":100}
+//	GET  /v1/decode?name=user&id=123        POST /snowflake.v1.IDService/Decode {"name":"user","id":"123"}
+//	GET  /healthz
+type Server struct {
+	registry *snowflake.Registry
+	mux      *http.ServeMux
+	stopped  atomic.Bool
+}
+
+type Request struct {
+	Name string        `json:"name"`
+	N    int           `json:"n,omitempty"`
+	ID   snowflake.UID `json:"id,omitempty"`
+}
+
+type NextResponse struct {
+	ID snowflake.UID `json:"id"`
+}
+
+type BatchResponse struct {
+	IDs []snowflake.UID `json:"ids"`
+}
+
+type DecodeResponse struct {
+	ID           snowflake.UID `json:"id"`
+	Time         time.Time     `json:"time"`
+	Timestamp    int64         `json:"timestamp"`
+	DatacenterID int64         `json:"datacenter_id"`
+	MachineID    int64         `json:"machine_id"`
+	Sequence     int64         `json:"sequence"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(r *snowflake.Registry) *Server {
+	s := &Server{registry: r, mux: http.NewServeMux()}
+	s.handle("/v1/next", "/snowflake.v1.IDService/Next", s.next)
+	s.handle("/v1/batch", "/snowflake.v1.IDService/Batch", s.batch)
+	s.handle("/v1/decode", "/snowflake.v1.IDService/Decode", s.decode)
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		if s.stopped.Load() {
+			writeError(w, http.StatusServiceUnavailable, ErrStopped)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return s
+}
+
+// Stop makes the server fail the requests for new IDs at once, e.g. when the
+// machine id lease is lost. Decode requests are still served.
+func (s *Server) Stop() {
+	s.stopped.Store(true)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve serves HTTP on l until ctx is done, then shuts down gracefully,
+// waiting up to 5s for the in-flight requests.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 5 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type handlerFunc func(ctx context.Context, req *Request) (any, error)
+
+// handle 注册REST和gRPC两种风格的路由，参数分别来自query和JSON body
+func (s *Server) handle(rest, rpc string, f handlerFunc) {
+	s.mux.HandleFunc(rest, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		req, err := parseQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		serve(w, r, req, f)
+	})
+	s.mux.HandleFunc(rpc, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		req := new(Request)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		serve(w, r, req, f)
+	})
+}
+
+func parseQuery(r *http.Request) (req *Request, err error) {
+	q := r.URL.Query()
+	req = &Request{Name: q.Get("name")}
+	if n := q.Get("n"); n != "" {
+		if req.N, err = strconv.Atoi(n); err != nil {
+			return nil, fmt.Errorf("invalid n %q", n)
+		}
+	}
+	if id := q.Get("id"); id != "" {
+		if req.ID, err = snowflake.ParseUID(id); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func serve(w http.ResponseWriter, r *http.Request, req *Request, f handlerFunc) {
+	if req.Name == "" {
+		req.Name = DefaultName
+	}
+	// 客户端断开或服务关闭时不再等待时钟
+	resp, err := f(r.Context(), req)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, resp)
+	case errors.Is(err, snowflake.ErrUnknownGenerator):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, errBadRequest), errors.Is(err, snowflake.ErrInvalidID),
+		errors.Is(err, snowflake.ErrInvalidEID), errors.Is(err, snowflake.ErrChecksum):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusServiceUnavailable, err)
+	}
+}
+
+var errBadRequest = errors.New("bad request")
+
+// batcher 可以一次生成多个ID，例如 snowflake.ID
+type batcher interface {
+	NextNContext(ctx context.Context, n int) ([]uint64, error)
+}
+
+// decomposer 可以解析生成的ID，例如 snowflake.ID 和 snowflake.AtomicID
+type decomposer interface {
+	Decompose(v uint64) (snowflake.Parts, error)
+}
+
+func (s *Server) generator(name string) (snowflake.IDGenerator, error) {
+	g, ok := s.registry.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", snowflake.ErrUnknownGenerator, name)
+	}
+	return g, nil
+}
+
+func (s *Server) next(ctx context.Context, req *Request) (any, error) {
+	id, err := s.generator(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := id.NextIDContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 生成期间可能已经停止，此时丢弃ID
+	if s.stopped.Load() {
+		return nil, ErrStopped
+	}
+	return &NextResponse{ID: snowflake.UID(v)}, nil
+}
+
+func (s *Server) batch(ctx context.Context, req *Request) (any, error) {
+	if req.N <= 0 || req.N > MaxBatch {
+		return nil, fmt.Errorf("%w: n must be in [1, %d]", errBadRequest, MaxBatch)
+	}
+	id, err := s.generator(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := nextN(ctx, id, req.N)
+	if err != nil {
+		return nil, err
+	}
+	if s.stopped.Load() {
+		return nil, ErrStopped
+	}
+	resp := &BatchResponse{IDs: make([]snowflake.UID, len(ids))}
+	for i, v := range ids {
+		resp.IDs[i] = snowflake.UID(v)
+	}
+	return resp, nil
+}
+
+func (s *Server) decode(_ context.Context, req *Request) (any, error) {
+	id, err := s.generator(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := id.(decomposer)
+	if !ok {
+		return nil, fmt.Errorf("%w: generator %s can not decode ids", errBadRequest, req.Name)
+	}
+	p, err := d.Decompose(uint64(req.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &DecodeResponse{
+		ID:           req.ID,
+		Time:         p.Time,
+		Timestamp:    p.Timestamp,
+		DatacenterID: p.DatacenterID,
+		MachineID:    p.MachineID,
+		Sequence:     p.Sequence,
+	}, nil
+}
+
+// nextN 生成n个ID，生成器不支持批量时逐个生成
+func nextN(ctx context.Context, g snowflake.IDGenerator, n int) ([]uint64, error) {
+	if b, ok := g.(batcher); ok {
+		return b.NextNContext(ctx, n)
+	}
+	ids := make([]uint64, n)
+	for i := range ids {
+		v, err := g.NextIDContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = v
+	}
+	return ids, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &ErrorResponse{Error: err.Error()})
+}
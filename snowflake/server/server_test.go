@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	r := snowflake.NewRegistry()
+	_, err := r.Register(DefaultName, &snowflake.Options{MachineID: 3, Layout: snowflake.SnowflakeLayout})
+	assert.NoError(t, err)
+	_, err = r.Register("user", &snowflake.Options{MachineID: 3, Layout: snowflake.UID32Layout})
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(New(r))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getJSON(t *testing.T, url string, code int, v any) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, code, resp.StatusCode, url)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func postJSON(t *testing.T, url string, req any, code int, v any) {
+	data, _ := json.Marshal(req)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, code, resp.StatusCode, url)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func TestServer(t *testing.T) {
+	ts := newTestServer(t)
+
+	var next NextResponse
+	getJSON(t, ts.URL+"/v1/next", http.StatusOK, &next)
+	assert.NotZero(t, next.ID)
+
+	var decoded DecodeResponse
+	getJSON(t, ts.URL+"/v1/decode?id="+next.ID.String(), http.StatusOK, &decoded)
+	assert.Equal(t, next.ID, decoded.ID)
+	assert.Equal(t, int64(3), decoded.MachineID)
+	assert.Equal(t, int64(0), decoded.DatacenterID)
+	assert.WithinDuration(t, time.Now(), decoded.Time, time.Second)
+
+	var batch BatchResponse
+	getJSON(t, ts.URL+"/v1/batch?name=user&n=5", http.StatusOK, &batch)
+	assert.Len(t, batch.IDs, 5)
+
+	postJSON(t, ts.URL+"/snowflake.v1.IDService/Batch", &Request{Name: "user", N: 3}, http.StatusOK, &batch)
+	assert.Len(t, batch.IDs, 3)
+	postJSON(t, ts.URL+"/snowflake.v1.IDService/Next", &Request{}, http.StatusOK, &next)
+	assert.NotZero(t, next.ID)
+	postJSON(t, ts.URL+"/snowflake.v1.IDService/Decode", &Request{ID: next.ID}, http.StatusOK, &decoded)
+	assert.Equal(t, next.ID, decoded.ID)
+
+	var health map[string]string
+	getJSON(t, ts.URL+"/healthz", http.StatusOK, &health)
+	assert.Equal(t, "ok", health["status"])
+}
+
+func TestServer_Error(t *testing.T) {
+	ts := newTestServer(t)
+
+	var e ErrorResponse
+	getJSON(t, ts.URL+"/v1/next?name=order", http.StatusNotFound, &e)
+	assert.NotEmpty(t, e.Error)
+	getJSON(t, ts.URL+"/v1/batch?n=0", http.StatusBadRequest, &e)
+	getJSON(t, ts.URL+"/v1/batch?n=abc", http.StatusBadRequest, &e)
+	getJSON(t, ts.URL+"/v1/decode?id=18446744073709551615", http.StatusBadRequest, &e)
+	postJSON(t, ts.URL+"/v1/next", &Request{}, http.StatusMethodNotAllowed, &e)
+	postJSON(t, ts.URL+"/snowflake.v1.IDService/Batch", &Request{N: MaxBatch + 1}, http.StatusBadRequest, &e)
+}
+
+func TestServer_Serve(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- New(snowflake.NewRegistry()).Serve(ctx, ln)
+	}()
+
+	var health map[string]string
+	getJSON(t, "http://"+ln.Addr().String()+"/healthz", http.StatusOK, &health)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server should shut down")
+	}
+}
+
+func TestServer_Stop(t *testing.T) {
+	r := snowflake.NewRegistry()
+	_, err := r.Register(DefaultName, &snowflake.Options{MachineID: 3, Layout: snowflake.SnowflakeLayout})
+	assert.NoError(t, err)
+	s := New(r)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	var next NextResponse
+	getJSON(t, ts.URL+"/v1/next", http.StatusOK, &next)
+
+	// 停止后立即拒绝发号，解析ID不受影响
+	s.Stop()
+	var e ErrorResponse
+	getJSON(t, ts.URL+"/v1/next", http.StatusServiceUnavailable, &e)
+	assert.Equal(t, ErrStopped.Error(), e.Error)
+	getJSON(t, ts.URL+"/v1/batch?n=2", http.StatusServiceUnavailable, &e)
+	getJSON(t, ts.URL+"/healthz", http.StatusServiceUnavailable, &e)
+	var decoded DecodeResponse
+	getJSON(t, ts.URL+"/v1/decode?id="+next.ID.String(), http.StatusOK, &decoded)
+}
+
+type counter struct{ n uint64 }
+
+func (c *counter) NextIDContext(context.Context) (uint64, error) {
+	c.n++
+	return c.n, nil
+}
+
+func TestServer_Generators(t *testing.T) {
+	r := snowflake.NewRegistry()
+	atomicID, err := snowflake.NewAtomic(&snowflake.Options{MachineID: 3, Layout: snowflake.SnowflakeLayout})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Add("order", atomicID))
+	eid, err := snowflake.NewEIDGenerator(&snowflake.EIDOptions{Options: snowflake.Options{MachineID: 3}, CheckDigit: true})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Add("enterprise", eid))
+	assert.NoError(t, r.Add("counter", &counter{}))
+	ts := httptest.NewServer(New(r))
+	t.Cleanup(ts.Close)
+
+	var (
+		next    NextResponse
+		batch   BatchResponse
+		decoded DecodeResponse
+		e       ErrorResponse
+	)
+	for _, name := range []string{"order", "enterprise"} {
+		getJSON(t, ts.URL+"/v1/next?name="+name, http.StatusOK, &next)
+		getJSON(t, ts.URL+"/v1/decode?name="+name+"&id="+next.ID.String(), http.StatusOK, &decoded)
+		assert.Equal(t, int64(3), decoded.MachineID, name)
+		getJSON(t, ts.URL+"/v1/batch?n=3&name="+name, http.StatusOK, &batch)
+		assert.Len(t, batch.IDs, 3)
+	}
+	getJSON(t, ts.URL+"/v1/decode?name=enterprise&id=123", http.StatusBadRequest, &e)
+
+	// 不支持批量的生成器逐个生成，不支持解析时返回400
+	getJSON(t, ts.URL+"/v1/batch?n=3&name=counter", http.StatusOK, &batch)
+	assert.Equal(t, []snowflake.UID{1, 2, 3}, batch.IDs)
+	getJSON(t, ts.URL+"/v1/decode?name=counter&id=1", http.StatusBadRequest, &e)
+}
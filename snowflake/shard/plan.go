@@ -0,0 +1,130 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var ErrIncompatible = errors.New("shard: incompatible routers")
+
+// maxModuloKeys 限制取模方案的键空间，即两个模数的最小公倍数
+const maxModuloKeys = 1 << 20
+
+// Move is a range of keys [First, Last] moving from one shard to another.
+type Move struct {
+	First uint64
+	Last  uint64
+	From  int
+	To    int
+}
+
+// Plan lists the keys which move between shards when resharding from one
+// router to another of the same kind. The key of an ID is the ID itself for
+// RangeTable, and a hash of the ID for Ring and Modulo, see Plan.Key.
+type Plan struct {
+	Moves []Move // 按First升序排列
+
+	key  func(id uint64) uint64
+	size float64 // 键空间的大小
+}
+
+// NewPlan compares the routers, both must be of the same kind.
+func NewPlan(from, to Router) (*Plan, error) {
+	switch f := from.(type) {
+	case *RangeTable:
+		if t, ok := to.(*RangeTable); ok {
+			return newPlan(f.rs, t.rs, func(id uint64) uint64 { return id }, math.MaxUint64), nil
+		}
+	case *Ring:
+		if t, ok := to.(*Ring); ok {
+			return newPlan(f.ranges(), t.ranges(), hash, math.MaxUint64), nil
+		}
+	case Modulo:
+		if t, ok := to.(Modulo); ok {
+			return newModuloPlan(f, t)
+		}
+	}
+	return nil, fmt.Errorf("%w: %T and %T", ErrIncompatible, from, to)
+}
+
+// newModuloPlan 在哈希值对两个模数最小公倍数取模的键空间上比较
+func newModuloPlan(from, to Modulo) (*Plan, error) {
+	n := uint64(from) / gcd(uint64(from), uint64(to)) * uint64(to)
+	if n > maxModuloKeys {
+		return nil, fmt.Errorf("%w: lcm of %d and %d is too large", ErrIncompatible, from, to)
+	}
+	ranges := func(m Modulo) []Range {
+		rs := make([]Range, n)
+		for k := uint64(0); k < n; k++ {
+			rs[k] = Range{First: k, Last: k, Shard: int(k % uint64(m))}
+		}
+		return rs
+	}
+	key := func(id uint64) uint64 { return hash(id) % n }
+	return newPlan(ranges(from), ranges(to), key, float64(n)), nil
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// newPlan 同时遍历两组覆盖整个键空间的有序区间，记录分片不同的部分
+func newPlan(from, to []Range, key func(uint64) uint64, size float64) *Plan {
+	p := &Plan{key: key, size: size}
+	var first uint64
+	for i, j := 0, 0; i < len(from) && j < len(to); {
+		a, b := from[i], to[j]
+		last := a.Last
+		if b.Last < last {
+			last = b.Last
+		}
+		if a.Shard != b.Shard {
+			// 与相邻且方向相同的迁移合并
+			if n := len(p.Moves); n > 0 && p.Moves[n-1].Last+1 == first &&
+				p.Moves[n-1].From == a.Shard && p.Moves[n-1].To == b.Shard {
+				p.Moves[n-1].Last = last
+			} else {
+				p.Moves = append(p.Moves, Move{First: first, Last: last, From: a.Shard, To: b.Shard})
+			}
+		}
+		if a.Last == last {
+			i++
+		}
+		if b.Last == last {
+			j++
+		}
+		first = last + 1
+	}
+	return p
+}
+
+// Key returns the key of id which the moves refer to.
+func (p *Plan) Key(id uint64) uint64 {
+	return p.key(id)
+}
+
+// Move returns the move of id, false if id stays on its shard.
+func (p *Plan) Move(id uint64) (Move, bool) {
+	k := p.key(id)
+	i := sort.Search(len(p.Moves), func(i int) bool { return p.Moves[i].Last >= k })
+	if i < len(p.Moves) && p.Moves[i].First <= k {
+		return p.Moves[i], true
+	}
+	return Move{}, false
+}
+
+// Fraction returns the fraction of the key space which moves, about the
+// fraction of IDs for Ring and Modulo. For RangeTable it depends on how the
+// IDs are distributed, check the moves against the data instead.
+func (p *Plan) Fraction() float64 {
+	var moved float64
+	for _, m := range p.Moves {
+		moved += float64(m.Last-m.First) + 1
+	}
+	return moved / p.size
+}
@@ -0,0 +1,195 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+var ErrInvalidConfig = errors.New("shard: invalid config")
+
+// Router maps an ID to the index of its shard, the same ID is always routed
+// to the same shard by routers of the same configuration.
+type Router interface {
+	Shard(id uint64) int
+}
+
+// Range is the shard owning the keys in [First, Last].
+type Range struct {
+	First uint64
+	Last  uint64
+	Shard int
+}
+
+// hash 打散ID：雪花ID的低位是序列号，低并发时大多为0，直接取模会严重倾斜
+func hash(v uint64) uint64 {
+	v ^= v >> 30
+	v *= 0xbf58476d1ce4e5b9
+	v ^= v >> 27
+	v *= 0x94d049bb133111eb
+	v ^= v >> 31
+	return v
+}
+
+// Modulo routes the hash of an ID modulo N, it spreads IDs evenly but moves
+// most of them when N changes.
+type Modulo int
+
+func NewModulo(n int) (Modulo, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: modulo must be positive, got %d", ErrInvalidConfig, n)
+	}
+	return Modulo(n), nil
+}
+
+func (m Modulo) Shard(id uint64) int {
+	return int(hash(id) % uint64(m))
+}
+
+// Ring is a consistent hash ring, adding or removing a shard only moves the
+// IDs of about 1/N of the ring.
+type Ring struct {
+	points []uint64 // 升序排列的虚拟节点
+	shards []int    // 虚拟节点对应的分片
+}
+
+// DefaultReplicas is the number of virtual nodes per shard when NewRing is
+// given no positive replicas.
+const DefaultReplicas = 160
+
+// NewRing creates a ring of the shards, each placed on the ring replicas times.
+func NewRing(shards []int, replicas int) (*Ring, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%w: ring has no shard", ErrInvalidConfig)
+	}
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	seen := make(map[int]bool, len(shards))
+	type point struct {
+		hash  uint64
+		shard int
+	}
+	points := make([]point, 0, len(shards)*replicas)
+	for _, s := range shards {
+		if s < 0 || seen[s] {
+			return nil, fmt.Errorf("%w: invalid or duplicate shard %d", ErrInvalidConfig, s)
+		}
+		seen[s] = true
+		for i := 0; i < replicas; i++ {
+			points = append(points, point{hash(uint64(s)<<32 | uint64(i)), s})
+		}
+	}
+	// 哈希冲突时保留分片较小的虚拟节点，保证与分片顺序无关
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return points[i].shard < points[j].shard
+	})
+	r := &Ring{}
+	for i, p := range points {
+		if i > 0 && p.hash == points[i-1].hash {
+			continue
+		}
+		r.points = append(r.points, p.hash)
+		r.shards = append(r.shards, p.shard)
+	}
+	return r, nil
+}
+
+func (r *Ring) Shard(id uint64) int {
+	return r.owner(hash(id))
+}
+
+// owner 返回顺时针方向第一个虚拟节点的分片
+func (r *Ring) owner(key uint64) int {
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= key })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.shards[i]
+}
+
+// ranges 返回环上每一段哈希值所属的分片
+func (r *Ring) ranges() []Range {
+	first := r.shards[0]
+	rs := []Range{{First: 0, Last: r.points[0], Shard: first}}
+	for i := 1; i < len(r.points); i++ {
+		rs = append(rs, Range{First: r.points[i-1] + 1, Last: r.points[i], Shard: r.shards[i]})
+	}
+	if last := r.points[len(r.points)-1]; last < math.MaxUint64 {
+		rs = append(rs, Range{First: last + 1, Last: math.MaxUint64, Shard: first})
+	}
+	return rs
+}
+
+// RangeTable routes IDs by value, e.g. by the time they were created, see
+// TimeBound. Unlike Modulo and Ring, the IDs of a shard are contiguous.
+type RangeTable struct {
+	rs []Range
+}
+
+// NewRangeTable creates a table where shards[0] owns the IDs below bounds[0],
+// shards[i] owns [bounds[i-1], bounds[i]) and the last shard owns the rest.
+func NewRangeTable(shards []int, bounds []uint64) (*RangeTable, error) {
+	if len(shards) == 0 || len(bounds) != len(shards)-1 {
+		return nil, fmt.Errorf("%w: %d shards need %d bounds, got %d",
+			ErrInvalidConfig, len(shards), len(shards)-1, len(bounds))
+	}
+	t := &RangeTable{rs: make([]Range, len(shards))}
+	var first uint64
+	for i, s := range shards {
+		if s < 0 {
+			return nil, fmt.Errorf("%w: invalid shard %d", ErrInvalidConfig, s)
+		}
+		last := uint64(math.MaxUint64)
+		if i < len(bounds) {
+			if bounds[i] <= first {
+				return nil, fmt.Errorf("%w: bounds must be ascending and positive", ErrInvalidConfig)
+			}
+			last = bounds[i] - 1
+		}
+		t.rs[i] = Range{First: first, Last: last, Shard: s}
+		first = last + 1
+	}
+	return t, nil
+}
+
+func (t *RangeTable) Shard(id uint64) int {
+	i := sort.Search(len(t.rs), func(i int) bool { return t.rs[i].Last >= id })
+	return t.rs[i].Shard
+}
+
+// Ranges returns the ranges of IDs owned by each shard in ascending order.
+func (t *RangeTable) Ranges() []Range {
+	return append([]Range(nil), t.rs...)
+}
+
+// TimeBound returns the smallest ID of the layout generated at t or later,
+// to be used as a bound of a RangeTable, e.g. one shard per year.
+// Truncating layouts wrap and cannot be routed by time.
+func TimeBound(l snowflake.Layout, t time.Time) (uint64, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+	if l.Truncate {
+		return 0, fmt.Errorf("%w: truncating layout cannot be routed by time", ErrInvalidConfig)
+	}
+	if t.Before(l.Epoch) {
+		return 0, fmt.Errorf("%w: %s is before the epoch", ErrInvalidConfig, t)
+	}
+	d := t.Sub(l.Epoch)
+	ts := uint64(d / l.TimeUnit)
+	if d%l.TimeUnit != 0 {
+		ts++
+	}
+	if l.TimestampBits < 64 && ts >= 1<<l.TimestampBits {
+		return 0, fmt.Errorf("%w: %s is after the layout expires", ErrInvalidConfig, t)
+	}
+	return ts << (l.Bits() - l.TimestampBits), nil
+}
@@ -0,0 +1,155 @@
+package shard
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adobaai/studio_common/snowflake"
+)
+
+// snowflakeIDs 生成低并发下的雪花ID，序列号大多为0
+func snowflakeIDs(t *testing.T, n int) []uint64 {
+	clock := snowflake.NewFakeClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	id, err := snowflake.New(&snowflake.Options{MachineID: 1, Layout: snowflake.SnowflakeLayout, Clock: clock})
+	assert.NoError(t, err)
+	ids := make([]uint64, n)
+	for i := range ids {
+		clock.Add(time.Duration(rand.Intn(10)+1) * time.Millisecond)
+		ids[i], err = id.NextID()
+		assert.NoError(t, err)
+	}
+	return ids
+}
+
+func TestModulo(t *testing.T) {
+	_, err := NewModulo(0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	m, err := NewModulo(8)
+	assert.NoError(t, err)
+	counts := make([]int, 8)
+	for _, v := range snowflakeIDs(t, 8000) {
+		s := m.Shard(v)
+		assert.Equal(t, s, m.Shard(v))
+		counts[s]++
+	}
+	// 序列号均为0时仍然分布均匀
+	for s, c := range counts {
+		assert.InDelta(t, 1000, c, 200, "shard %d", s)
+	}
+}
+
+func TestRing(t *testing.T) {
+	_, err := NewRing(nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = NewRing([]int{1, 1}, 0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	r1, err := NewRing([]int{0, 1, 2, 3}, 0)
+	assert.NoError(t, err)
+	r2, err := NewRing([]int{3, 2, 1, 0}, 0)
+	assert.NoError(t, err)
+
+	counts := make(map[int]int)
+	for _, v := range snowflakeIDs(t, 8000) {
+		assert.Equal(t, r1.Shard(v), r2.Shard(v))
+		counts[r1.Shard(v)]++
+	}
+	for s, c := range counts {
+		assert.InDelta(t, 2000, c, 600, "shard %d", s)
+	}
+}
+
+func TestRangeTable(t *testing.T) {
+	_, err := NewRangeTable([]int{0, 1}, nil)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = NewRangeTable([]int{0, 1, 2}, []uint64{100, 100})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	tb, err := NewRangeTable([]int{0, 1, 2}, []uint64{100, 200})
+	assert.NoError(t, err)
+	for id, want := range map[uint64]int{0: 0, 99: 0, 100: 1, 199: 1, 200: 2, math.MaxUint64: 2} {
+		assert.Equal(t, want, tb.Shard(id), "id %d", id)
+	}
+}
+
+func TestTimeBound(t *testing.T) {
+	l := snowflake.SnowflakeLayout
+	y2024 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b, err := TimeBound(l, y2024)
+	assert.NoError(t, err)
+
+	tb, err := NewRangeTable([]int{0, 1}, []uint64{b})
+	assert.NoError(t, err)
+	for _, v := range snowflakeIDs(t, 10) {
+		assert.Equal(t, 1, tb.Shard(v))
+	}
+	p, err := l.Decompose(b)
+	assert.NoError(t, err)
+	assert.True(t, p.Time.Equal(y2024))
+	p, err = l.Decompose(b - 1)
+	assert.NoError(t, err)
+	assert.True(t, p.Time.Before(y2024))
+
+	_, err = TimeBound(snowflake.UIDLayout, y2024)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = TimeBound(l, l.Epoch.Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+// checkPlan 检查计划中的迁移与两个Router的路由结果一致
+func checkPlan(t *testing.T, from, to Router, ids []uint64) *Plan {
+	p, err := NewPlan(from, to)
+	assert.NoError(t, err)
+	for _, v := range ids {
+		m, moved := p.Move(v)
+		if from.Shard(v) == to.Shard(v) {
+			assert.False(t, moved, "id %d", v)
+			continue
+		}
+		if assert.True(t, moved, "id %d", v) {
+			assert.Equal(t, from.Shard(v), m.From)
+			assert.Equal(t, to.Shard(v), m.To)
+		}
+	}
+	return p
+}
+
+func TestPlan(t *testing.T) {
+	ids := snowflakeIDs(t, 5000)
+
+	t.Run("range", func(t *testing.T) {
+		mid := ids[len(ids)/2]
+		from, _ := NewRangeTable([]int{0, 1}, []uint64{ids[0]})
+		to, _ := NewRangeTable([]int{0, 1, 2}, []uint64{ids[0], mid})
+		p := checkPlan(t, from, to, ids)
+		assert.Equal(t, []Move{{First: mid, Last: math.MaxUint64, From: 1, To: 2}}, p.Moves)
+	})
+
+	t.Run("ring", func(t *testing.T) {
+		from, _ := NewRing([]int{0, 1, 2, 3}, 0)
+		to, _ := NewRing([]int{0, 1, 2, 3, 4}, 0)
+		p := checkPlan(t, from, to, ids)
+		// 新增一个分片只迁移约1/5的ID，并且只迁移到新分片
+		assert.InDelta(t, 0.2, p.Fraction(), 0.07)
+		for _, m := range p.Moves {
+			assert.Equal(t, 4, m.To)
+		}
+	})
+
+	t.Run("modulo", func(t *testing.T) {
+		p := checkPlan(t, Modulo(4), Modulo(6), ids)
+		assert.InDelta(t, 2.0/3, p.Fraction(), 1e-9)
+		p = checkPlan(t, Modulo(4), Modulo(8), ids)
+		assert.InDelta(t, 0.5, p.Fraction(), 1e-9)
+	})
+
+	_, err := NewPlan(Modulo(4), &Ring{})
+	assert.ErrorIs(t, err, ErrIncompatible)
+	_, err = NewPlan(Modulo(1<<20), Modulo(1<<20-1))
+	assert.ErrorIs(t, err, ErrIncompatible)
+}
@@ -1,18 +1,35 @@
 package snowflake
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 	"sync"
 	"time"
 )
 
+var ErrEpochOverflow = errors.New("snowflake: timestamp overflows the layout")
+
 type ID struct {
 	sync.Mutex
-	timestamp int64 // 单位ms
+	layout    Layout
+	timestamp int64 // 单位: layout.TimeUnit
 	sequence  int64 // 序列号
 	machineID int64 // 机器id
+	node      int64 // 拼接了数据中心id的机器id
+	clock     Clock
+	observer  Observer
+	rollback  RollbackPolicy
+	tolerance time.Duration // 小于0表示不限制
+	store     CheckpointStore
+	interval  int64 // 检查点间隔，单位: layout.TimeUnit
+	reserved  int64 // 已保存的检查点
+	warnAt    int64 // 到达该时间戳时通知observer即将到期
+	batch     bool  // NextN执行期间等待时钟不释放锁
 }
 
 const (
@@ -21,62 +38,309 @@ const (
 	sequenceBits  uint8 = 12                        // 序列号所占的位数
 	maxSequence   int64 = -1 ^ (-1 << sequenceBits) // 支持的最大序列号数量
 	maxMachineId  int64 = -1 ^ (-1 << machineIDBits)
-	keep32Bits    int64 = -1 ^ (-1 << 32) + 1 // %b: 1 0000 0000 0000 0000 0000 0000 0000 0000
 )
 
+// NewIDGenerator creates a UIDLayout generator with the machine id, which is
+// replaced with a random one if it is not in [1, 31].
+//
+// Deprecated: a random machine id may collide with another machine.
+// Use New, which fails on an invalid machine id, with Options.MachineIDSource.
 func NewIDGenerator(mid int64) *ID {
-	id := new(ID)
 	if mid > maxMachineId || mid < 1 {
-		num, _ := rand.Int(rand.Reader, big.NewInt(maxMachineId+1))
-		mid = num.Int64()
+		actual := randomMachineID()
+		DefaultObserver.MachineIDFallback(mid, actual)
+		mid = actual
+	}
+	// 时钟回拨时沿用上次的时间戳，保证不会生成重复的ID
+	return &ID{
+		layout:    UIDLayout,
+		machineID: mid,
+		node:      mid,
+		rollback:  RollbackBorrow,
+		tolerance: -1,
+		clock:     SystemClock,
+		observer:  DefaultObserver,
+		warnAt:    math.MaxInt64,
+	}
+}
+
+func randomMachineID() int64 {
+	num, err := rand.Int(rand.Reader, big.NewInt(maxMachineId))
+	if err != nil {
+		// 随机数不可用时退化为使用当前时间
+		return time.Now().UnixNano()%maxMachineId + 1
+	}
+	return num.Int64() + 1
+}
+
+// New creates a generator with the given options.
+// Unlike NewIDGenerator, an out of range machine id is an error.
+func New(op *Options) (*ID, error) {
+	if err := initConfig(op); err != nil {
+		return nil, err
+	}
+	id := &ID{
+		layout:    op.Layout,
+		machineID: op.MachineID,
+		node:      op.Layout.node(op.DatacenterID, op.MachineID),
+		rollback:  op.Rollback,
+		tolerance: op.RollbackTolerance,
+		clock:     op.Clock,
+		observer:  op.Observer,
+		warnAt:    warnAt(op.Layout, op.ExpiryWarning),
+	}
+	if op.Checkpoint != nil {
+		if err := id.initCheckpoint(op); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+func (id *ID) initCheckpoint(op *Options) error {
+	interval := op.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	id.store = op.Checkpoint
+	id.interval = int64((interval + id.layout.TimeUnit - 1) / id.layout.TimeUnit)
+
+	t, err := id.store.Load()
+	if err != nil || t.IsZero() {
+		return err
+	}
+	id.timestamp = id.layout.timestamp(t)
+	id.reserved = id.timestamp
+
+	// 检查点最多领先一个间隔，重启较快时等待时钟越过检查点
+	ahead := t.Sub(id.clock.Now())
+	if ahead > 0 && ahead <= interval && id.rollback != RollbackBorrow {
+		_, err = id.wait(context.Background(), id.timestamp-1, ErrClockMovedBackwards)
+	}
+	return err
+}
+
+// Layout returns the layout of the generated IDs.
+func (id *ID) Layout() Layout {
+	return id.layout
+}
+
+// Decompose splits v, an ID generated with the same layout, into its parts.
+func (id *ID) Decompose(v uint64) (Parts, error) {
+	return id.layout.decompose(v, id.clock.Now())
+}
+
+// Lifetime returns the remaining lifetime of the generator's layout.
+func (id *ID) Lifetime() Lifetime {
+	return id.layout.Lifetime(id.clock.Now())
+}
+
+// NextID returns the next ID of the generator's layout.
+func (id *ID) NextID() (uint64, error) {
+	return id.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID but gives up with a WaitError once ctx is done
+// while waiting for the clock, i.e. when the sequence is exhausted or the
+// clock moved backwards under RollbackWait. The lock is released while
+// waiting, so other calls can still give up on their own ctx.
+func (id *ID) NextIDContext(ctx context.Context) (uint64, error) {
+	id.Lock()
+	defer id.Unlock()
+	v, err := id.next(ctx, false)
+	if err == nil {
+		id.observer.IDIssued(1)
+	}
+	return v, err
+}
+
+// NextN returns n IDs in a single locked operation, e.g. for a bulk insert.
+// The IDs are contiguous within each time unit and move on to the next unit
+// when the sequence is exhausted, following the same clock rules as NextID.
+// The lock is held while waiting for the clock, so no other call takes IDs
+// in between and other calls block until the batch is done.
+func (id *ID) NextN(n int) ([]uint64, error) {
+	return id.NextNContext(context.Background(), n)
+}
+
+// NextNContext is like NextN but honors ctx like NextIDContext.
+func (id *ID) NextNContext(ctx context.Context, n int) ([]uint64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	id.Lock()
+	defer id.Unlock()
+	id.batch = true
+	defer func() { id.batch = false }()
+
+	ids := make([]uint64, 0, n)
+	for len(ids) < n {
+		v, err := id.next(ctx, false)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, v)
+
+		// 用完当前时间单位剩余的序列号，不再读取时钟
+		for len(ids) < n && id.sequence < id.layout.MaxSequence() {
+			id.sequence++
+			if v, err = id.layout.compose(id.timestamp, id.node, id.sequence); err != nil {
+				return nil, err
+			}
+			ids = append(ids, v)
+		}
 	}
-	id.machineID = mid
-	return id
+	id.observer.IDIssued(n)
+	return ids, nil
 }
 
 // NextUID User ID
+//
+// NextUID only supports layouts of at most 32 bits, e.g. UIDLayout, and
+// panics on wider ones: use NextID for them.
+// NextUID never fails: whatever the rollback policy, it borrows the last
+// timestamp when the clock moves backwards. If the checkpoint cannot be
+// saved it borrows the time unit before the saved checkpoint and retries on
+// the next call. It returns 0 once that unit is used up or a non-truncating
+// layout has expired, which is never a valid ID as machine ids start from 1.
+// Use TryNextUID to apply the rollback policy and handle the errors.
 func (id *ID) NextUID() uint32 {
+	if err := id.uidLayout(); err != nil {
+		panic(err)
+	}
 	id.Lock()
 	defer id.Unlock()
+	v, err := id.next(context.Background(), true)
+	if err != nil {
+		return 0
+	}
+	id.observer.IDIssued(1)
+	return uint32(v)
+}
 
-	now := time.Now().UnixMilli()
-	if id.timestamp == now {
-		// 如果当前序列超出`maxSequence`长度，则需要等待下一毫秒
-		// 下一毫秒将设置sequence: 0
-		id.sequence = (id.sequence + 1) & maxSequence
-		if id.sequence == 0 {
-			for now <= id.timestamp {
-				now = time.Now().UnixMilli()
-			}
+// TryNextUID is like NextUID but returns the error, e.g. ErrClockMovedBackwards,
+// or ErrInvalidLayout for layouts wider than 32 bits.
+func (id *ID) TryNextUID() (uint32, error) {
+	return id.NextUIDContext(context.Background())
+}
+
+// NextUIDContext is like TryNextUID but honors ctx like NextIDContext.
+func (id *ID) NextUIDContext(ctx context.Context) (uint32, error) {
+	if err := id.uidLayout(); err != nil {
+		return 0, err
+	}
+	v, err := id.NextIDContext(ctx)
+	return uint32(v), err
+}
+
+// uidLayout 检查Layout能否放入uint32，否则截断后的ID不再唯一
+func (id *ID) uidLayout() error {
+	if bits := id.layout.Bits(); bits > 32 {
+		return fmt.Errorf("%w: %d bits exceed 32 bits, use NextID", ErrInvalidLayout, bits)
+	}
+	return nil
+}
+
+// next 生成下一个ID，force为true时回拨总是借用上次的时间戳，保存检查点失败时借用检查点前的时间戳（用于NextUID）。
+// 等待时钟时释放锁（NextN除外），醒来后重新读取时钟和生成器的状态，调用时必须持有锁
+func (id *ID) next(ctx context.Context, force bool) (uint64, error) {
+	var (
+		now, seq int64
+		start    time.Time // 序列号用尽后开始等待的时间
+	)
+	for {
+		ts, borrowed, err := id.tick(ctx, force)
+		if err != nil {
+			return 0, err
 		}
-	} else {
-		id.sequence = 0
+		now, seq = ts, 0
+		if id.timestamp == now {
+			// 如果当前序列超出`maxSequence`长度，则需要等待下一个时间单位
+			// 下一个时间单位将设置sequence: 0
+			seq = (id.sequence + 1) & id.layout.MaxSequence()
+		}
+		if id.timestamp != now || seq != 0 {
+			break
+		}
+		if start.IsZero() {
+			start = id.clock.Now()
+		}
+		if borrowed {
+			now++
+			break
+		}
+		if err = id.sleep(ctx, now+1, ErrSequenceExhausted); err != nil {
+			return 0, err
+		}
+	}
+	if !start.IsZero() {
+		id.observer.SequenceExhausted(id.clock.Now().Sub(start))
+	}
+	if err := id.checkpoint(now); err != nil {
+		if !force {
+			return 0, err
+		}
+		// 保存失败时不能越过已保存的检查点，借用检查点前的最后一个时间单位，序列号用尽后返回错误
+		last := id.reserved - 1
+		switch {
+		case id.timestamp < last:
+			now, seq = last, 0
+		case id.timestamp == last && id.sequence < id.layout.MaxSequence():
+			now, seq = last, id.sequence+1
+		default:
+			return 0, err
+		}
+	}
+	if now >= id.warnAt {
+		id.warnAt = math.MaxInt64
+		id.observer.LayoutExpiring(id.layout.expiry())
 	}
-	id.timestamp = now
+	id.timestamp, id.sequence = now, seq
 
-	// 1. 得到当前时间与预设的起始时间（epoch）之间的时间差：T1(ms)；
-	// 2. 将T1左移`(machineIDBits + sequenceBits)`位，保留足够的空间给机器ID和序列号使用；
-	// 3. 将机器ID移动到合适位置合并；
+	// 1. 得到当前时间与预设的起始时间（epoch）之间的时间差：T1；
+	// 2. 将T1左移`(DatacenterBits + NodeBits + SequenceBits)`位，保留足够的空间给数据中心ID、机器ID和序列号使用；
+	// 3. 将数据中心ID和机器ID移动到合适位置合并；
 	// 4. 合并序列号。
 	//
 	// 这样，生成的唯一标识符就能够在高位正确地包含时间戳，中间位包含节点ID，低位包含序列号。
-	t := ((id.timestamp - epoch) << (machineIDBits + sequenceBits)) |
-		(id.machineID << sequenceBits) |
-		id.sequence
+	return id.layout.compose(id.timestamp, id.node, id.sequence)
+}
 
-	// 如果t超出uint32范围，则进行截断处理
-	return uint32(t | keep32Bits)
+// sleep 释放锁睡眠到时间戳ts开始的时刻，返回前重新加锁；NextN执行期间持有锁睡眠
+func (id *ID) sleep(ctx context.Context, ts int64, cause error) error {
+	if id.batch {
+		return sleepUntil(ctx, id.clock, id.layout, ts, cause)
+	}
+	id.Unlock()
+	defer id.Lock()
+	return sleepUntil(ctx, id.clock, id.layout, ts, cause)
+}
+
+// wait 等待时钟越过last，返回新的时间戳；ctx结束时返回原因为cause的WaitError
+func (id *ID) wait(ctx context.Context, last int64, cause error) (int64, error) {
+	for {
+		now := id.layout.timestamp(id.clock.Now())
+		if now > last {
+			return now, nil
+		}
+		// 睡眠到下一个时间单位，而不是空转
+		if err := sleepUntil(ctx, id.clock, id.layout, last+1, cause); err != nil {
+			return 0, err
+		}
+	}
 }
 
 // NextEID Enterprise ID
+//
+// Deprecated: NextEID takes the sequence from the caller and overwrites the
+// time bits with the prefix, so it does not guarantee uniqueness.
+// Use EIDGenerator instead.
 func (id *ID) NextEID(sequence int64) uint32 {
-	now := time.Now().UnixMilli()
+	now := id.clock.Now().UnixMilli()
 
-	t := ((now - epoch) << (machineIDBits + sequenceBits)) |
-		(id.machineID << (sequenceBits)) |
-		sequence
+	t, _ := UIDLayout.compose(now-epoch, id.machineID, sequence)
 
-	num := uint32(t | keep32Bits)
+	num := uint32(t)
 	numStr := strconv.FormatUint(uint64(num), 10)
 	if len(numStr) > 4 {
 		numStr = "888" + numStr[4:]
@@ -1,13 +1,11 @@
 package snowflake
 
 import (
-	"bufio"
-	"fmt"
-	"log"
-	"os"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -15,15 +13,20 @@ const (
 	M        = 10000 * 100
 )
 
+// newTestGenerator creates a legacy UID generator driven by the clock.
+func newTestGenerator(t *testing.T, mid int64, c Clock) *ID {
+	id, err := New(&Options{MachineID: mid, Clock: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
 func Test_TwoMachinesGenerate100KUID(t *testing.T) {
 	mid := make(map[uint32]struct{})
-	start := time.Now().UnixMilli()
 	done := make(chan struct{})
 	ch := make(chan uint32, HundredK)
 
-	fr := NewFiler()
-	defer fr.close()
-
 	go func() {
 		for v := range ch {
 			_, exist := mid[v]
@@ -31,7 +34,6 @@ func Test_TwoMachinesGenerate100KUID(t *testing.T) {
 				t.Errorf("generated the repeated id: %d", v)
 				break
 			}
-			fr.write(v)
 			mid[v] = struct{}{}
 		}
 		done <- struct{}{}
@@ -41,9 +43,11 @@ func Test_TwoMachinesGenerate100KUID(t *testing.T) {
 	num := 2
 	wg.Add(num)
 
+	// 两台机器的时钟完全相同
+	start := time.Now()
 	for g := 1; g <= num; g++ {
 		go func(g int) {
-			id := NewIDGenerator(int64(g))
+			id := newTestGenerator(t, int64(g), NewFakeClock(start))
 			for i := 0; i < HundredK; i++ {
 				ch <- id.NextUID()
 			}
@@ -54,19 +58,14 @@ func Test_TwoMachinesGenerate100KUID(t *testing.T) {
 
 	close(ch)
 	<-done
-
-	fr.flush()
-	t.Logf("speed milli seconds: %d ms", time.Now().UnixMilli()-start)
 }
 
 func Test_Generates1MUID(t *testing.T) {
-	start := time.Now().UnixMilli()
-	id := NewIDGenerator(0)
+	start := time.Now()
+	c := NewFakeClock(start)
+	id := newTestGenerator(t, 1, c)
 	mid := make(map[uint32]struct{})
 
-	fr := NewFiler()
-	defer fr.close()
-
 	for i := 0; i < M; i++ {
 		v := id.NextUID()
 		_, exist := mid[v]
@@ -75,20 +74,15 @@ func Test_Generates1MUID(t *testing.T) {
 			return
 		}
 		mid[v] = struct{}{}
-		fr.write(v)
 	}
-
-	fr.flush()
-	t.Logf("speed milli seconds: %d ms ", time.Now().UnixMilli()-start)
+	t.Logf("simulated milli seconds: %d ms", c.Now().Sub(start).Milliseconds())
 }
 
 func Test_Generates4095EID(t *testing.T) {
 	var (
-		id  = NewIDGenerator(0)
+		id  = newTestGenerator(t, 1, NewFakeClock(time.Now()))
 		mid = make(map[uint32]struct{})
-		fr  = NewFiler()
 	)
-	defer fr.close()
 
 	var i int64 = 0
 	for ; i <= maxSequence; i++ {
@@ -99,44 +93,39 @@ func Test_Generates4095EID(t *testing.T) {
 			return
 		}
 		mid[v] = struct{}{}
-		fr.write(v)
 	}
-	fr.flush()
 }
 
-type Filer struct {
-	f *os.File
-	w *bufio.Writer
-}
+func TestID_NextN(t *testing.T) {
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	first, err := id.NextID()
+	assert.NoError(t, err)
 
-func NewFiler() (fr *Filer) {
-	fName := "id.txt"
-	_, err := os.Stat(fName)
-	var f *os.File
-	if os.IsNotExist(err) {
-		f, _ = os.Create(fName)
-	} else {
-		f, _ = os.OpenFile(fName, os.O_WRONLY|os.O_APPEND, 0666)
-	}
-	_ = f.Truncate(0)
-	fr = &Filer{
-		f: f,
-		w: bufio.NewWriter(f),
-	}
-	return
-}
+	ids, err := id.NextN(3 * int(maxSequence+1))
+	assert.NoError(t, err)
+	assert.Len(t, ids, 3*int(maxSequence+1))
 
-func (fr *Filer) write(uid uint32) {
-	_, err := fr.w.WriteString(fmt.Sprintf("%d\n", uid))
-	if err != nil {
-		log.Fatalf("write uid err: %v", err)
+	last := first
+	for i, v := range ids {
+		if v <= last {
+			t.Fatalf("the %dth id %d is not greater than %d", i, v, last)
+		}
+		p, _ := id.Decompose(v)
+		prev, _ := id.Decompose(last)
+		// 同一毫秒内的ID是连续的
+		if p.Timestamp == prev.Timestamp {
+			assert.Equal(t, last+1, v)
+		} else {
+			assert.Zero(t, p.Sequence)
+		}
+		last = v
 	}
-}
 
-func (fr *Filer) close() {
-	_ = fr.f.Close()
-}
+	next, err := id.NextID()
+	assert.NoError(t, err)
+	assert.Greater(t, next, last)
 
-func (fr *Filer) flush() {
-	_ = fr.w.Flush()
+	ids, err = id.NextN(0)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
 }
@@ -0,0 +1,214 @@
+package snowflake
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+var ErrChecksum = errors.New("snowflake: checksum mismatch")
+
+const (
+	base62Alphabet    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// base62Check 是Base62的校验字符，值为按位置加权和模67
+	base62Check = base62Alphabet + "-_.~*"
+	// crockfordCheck 是Crockford Base32的校验符号，值为ID模37
+	crockfordCheck = crockfordAlphabet + "*~$=U"
+)
+
+// UID is an ID generated by NextUID, NextEID or NextID with compact string
+// encodings. It is marshalled to JSON as a decimal string, as JavaScript
+// clients lose precision on 64bits numbers.
+//
+// UID implements sql.Scanner and driver.Valuer, so it can be used as a struct
+// field with sqlx and go-sqlbuilder, stored in a BIGINT column.
+type UID uint64
+
+// ParseUID parses the decimal form of an ID.
+func ParseUID(s string) (UID, error) {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	return UID(v), nil
+}
+
+func (u UID) String() string {
+	return strconv.FormatUint(uint64(u), 10)
+}
+
+// Hex returns the lowercase hexadecimal form without leading zeros.
+func (u UID) Hex() string {
+	return strconv.FormatUint(uint64(u), 16)
+}
+
+// ParseHex parses the form returned by Hex.
+func ParseHex(s string) (UID, error) {
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	return UID(v), nil
+}
+
+// Base62 returns the base62 form followed by a check character, which is
+// a base62 digit or one of "-_.~*".
+func (u UID) Base62() string {
+	var buf [12]byte
+	i := len(buf)
+	for v := uint64(u); ; v /= 62 {
+		i--
+		buf[i] = base62Alphabet[v%62]
+		if v < 62 {
+			break
+		}
+	}
+	s := buf[i:]
+	return string(append(s, base62Check[base62Checksum(s)]))
+}
+
+// ParseBase62 parses the form returned by Base62 and validates its check character.
+func ParseBase62(s string) (UID, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	digits, check := []byte(s[:len(s)-1]), s[len(s)-1]
+	var v uint64
+	for _, c := range digits {
+		d := strings.IndexByte(base62Alphabet, c)
+		if d < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+		}
+		hi, lo := bits.Mul64(v, 62)
+		lo, carry := bits.Add64(lo, uint64(d), 0)
+		if hi != 0 || carry != 0 {
+			return 0, fmt.Errorf("%w: %q overflows 64 bits", ErrInvalidID, s)
+		}
+		v = lo
+	}
+	if base62Check[base62Checksum(digits)] != check {
+		return 0, fmt.Errorf("%w: %q", ErrChecksum, s)
+	}
+	return UID(v), nil
+}
+
+// base62Checksum 从低位开始按位置加权求和后模67。67是大于62的素数，权重和字符差都小于67，
+// 因此可以发现所有单个字符错误和相邻字符交换
+func base62Checksum(s []byte) int {
+	sum := 0
+	for i, c := range s {
+		sum += (len(s) - i) * strings.IndexByte(base62Alphabet, c)
+	}
+	return sum % 67
+}
+
+// Base32 returns the Crockford base32 form followed by its check symbol.
+func (u UID) Base32() string {
+	var buf [14]byte
+	i := len(buf)
+	for v := uint64(u); ; v >>= 5 {
+		i--
+		buf[i] = crockfordAlphabet[v&31]
+		if v < 32 {
+			break
+		}
+	}
+	return string(append(buf[i:], crockfordCheck[uint64(u)%37]))
+}
+
+// ParseBase32 parses the form returned by Base32 and validates its check symbol.
+// As specified by Crockford, it is case-insensitive, reads I and L as 1,
+// O as 0, and ignores hyphens.
+func ParseBase32(s string) (UID, error) {
+	norm := strings.ToUpper(strings.ReplaceAll(s, "-", ""))
+	if len(norm) < 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	var v uint64
+	for _, c := range []byte(norm[:len(norm)-1]) {
+		switch c {
+		case 'I', 'L':
+			c = '1'
+		case 'O':
+			c = '0'
+		}
+		d := strings.IndexByte(crockfordAlphabet, c)
+		if d < 0 || v>>59 != 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+		}
+		v = v<<5 | uint64(d)
+	}
+	if crockfordCheck[v%37] != norm[len(norm)-1] {
+		return 0, fmt.Errorf("%w: %q", ErrChecksum, s)
+	}
+	return UID(v), nil
+}
+
+func (u UID) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(u.String())), nil
+}
+
+// UnmarshalJSON accepts both a decimal string and a number.
+func (u *UID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if len(data) > 1 && data[0] == '"' && data[len(data)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	v, err := ParseUID(s)
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+func (u UID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UID) UnmarshalText(text []byte) error {
+	v, err := ParseUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value implements driver.Valuer. IDs wider than 63 bits do not fit in a
+// signed BIGINT and are rejected.
+func (u UID) Value() (driver.Value, error) {
+	if u > math.MaxInt64 {
+		return nil, fmt.Errorf("%w: %d overflows int64", ErrInvalidID, u)
+	}
+	return int64(u), nil
+}
+
+// Scan implements sql.Scanner.
+func (u *UID) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("%w: %d is negative", ErrInvalidID, v)
+		}
+		*u = UID(v)
+		return nil
+	case uint64:
+		*u = UID(v)
+		return nil
+	case []byte:
+		return u.UnmarshalText(v)
+	case string:
+		return u.UnmarshalText([]byte(v))
+	}
+	return fmt.Errorf("snowflake: can not scan %T into UID", src)
+}
@@ -0,0 +1,137 @@
+package snowflake
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUID_Encoding(t *testing.T) {
+	id, _ := New(&Options{MachineID: 1, Layout: SnowflakeLayout})
+	v, _ := id.NextID()
+	values := []UID{0, 1, 61, 62, math.MaxUint32, math.MaxUint64, UID(v), UID(NewIDGenerator(1).NextEID(1))}
+	for i := 0; i < 1000; i++ {
+		values = append(values, UID(rand.Uint64()))
+	}
+
+	for _, u := range values {
+		p, err := ParseUID(u.String())
+		assert.NoError(t, err)
+		assert.Equal(t, u, p)
+
+		p, err = ParseHex(u.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, u, p)
+
+		p, err = ParseBase62(u.Base62())
+		assert.NoError(t, err, u.Base62())
+		assert.Equal(t, u, p)
+
+		p, err = ParseBase32(u.Base32())
+		assert.NoError(t, err, u.Base32())
+		assert.Equal(t, u, p)
+	}
+}
+
+func TestUID_Checksum(t *testing.T) {
+	u := UID(1234567890123)
+
+	s := u.Base62()
+	typo := []byte(s)
+	typo[1], typo[2] = typo[2], typo[1]
+	_, err := ParseBase62(string(typo))
+	assert.ErrorIs(t, err, ErrChecksum)
+	_, err = ParseBase62("0" + s)
+	assert.NoError(t, err)
+	_, err = ParseBase62("1" + s)
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	// "102"和"W02"在模62时校验字符相同
+	_, err = ParseBase62(UID(62).Base62()[:2] + UID(1984).Base62()[2:])
+	assert.Error(t, err)
+
+	s = u.Base32()
+	p, err := ParseBase32(strings.ToLower(s[:4]) + "-" + s[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, u, p)
+	_, err = ParseBase32("1" + s)
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	_, err = ParseBase32("U")
+	assert.ErrorIs(t, err, ErrInvalidID)
+	_, err = ParseBase62("!!")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestUID_JSON(t *testing.T) {
+	type user struct {
+		ID UID `json:"id"`
+	}
+	data, err := json.Marshal(user{ID: math.MaxUint64})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"18446744073709551615"}`, string(data))
+
+	var u user
+	assert.NoError(t, json.Unmarshal(data, &u))
+	assert.Equal(t, UID(math.MaxUint64), u.ID)
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":123}`), &u))
+	assert.Equal(t, UID(123), u.ID)
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"abc"}`), &u))
+}
+
+func TestUID_SQL(t *testing.T) {
+	type user struct {
+		ID   UID    `db:"id"`
+		Name string `db:"name"`
+	}
+	userStruct := sqlbuilder.NewStruct(new(user))
+
+	ib := userStruct.InsertInto("user", &user{ID: 1234567890123, Name: "studio"})
+	sqlStr, args := ib.Build()
+	sqlStr, err := sqlbuilder.MySQL.Interpolate(sqlStr, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO user (id, name) VALUES (1234567890123, 'studio')", sqlStr)
+
+	_, err = UID(math.MaxUint64).Value()
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	var u UID
+	for _, src := range []any{int64(42), uint64(42), []byte("42"), "42"} {
+		u = 0
+		assert.NoError(t, u.Scan(src))
+		assert.Equal(t, UID(42), u)
+	}
+	assert.Error(t, u.Scan(int64(-1)))
+	assert.Error(t, u.Scan(nil))
+
+	text, err := UID(42).MarshalText()
+	assert.NoError(t, err)
+	assert.NoError(t, u.UnmarshalText(text))
+	assert.Equal(t, UID(42), u)
+}
+
+func TestUID_Base62Substitution(t *testing.T) {
+	values := []UID{0, 62, 1984, math.MaxUint64}
+	for i := 0; i < 100; i++ {
+		values = append(values, UID(rand.Uint64()))
+	}
+	for _, u := range values {
+		s := u.Base62()
+		for i := range s {
+			for _, c := range []byte(base62Check) {
+				if c == s[i] {
+					continue
+				}
+				typo := []byte(s)
+				typo[i] = c
+				_, err := ParseBase62(string(typo))
+				assert.Error(t, err, "%s -> %s", s, typo)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrRandomOverflow = errors.New("snowflake: random part overflows within one millisecond")
+
+// UUIDv7Generator generates version 7 UUIDs (RFC 9562): 48 bits of unix
+// milliseconds followed by random bits. The 12 bits after the version are
+// a counter within the same millisecond, so the UUIDs are strictly increasing.
+type UUIDv7Generator struct {
+	sync.Mutex
+	rand      io.Reader
+	timestamp int64 // 单位ms
+	counter   uint16
+}
+
+func NewUUIDv7Generator() *UUIDv7Generator {
+	return &UUIDv7Generator{rand: rand.Reader}
+}
+
+func (g *UUIDv7Generator) Next() (u uuid.UUID, err error) {
+	if _, err = io.ReadFull(g.rand, u[6:]); err != nil {
+		return
+	}
+
+	g.Lock()
+	now := time.Now().UnixMilli()
+	if now > g.timestamp {
+		g.timestamp = now
+		// 计数器从随机值开始，留出一半空间用于递增
+		g.counter = binary.BigEndian.Uint16(u[6:8]) & 0x7ff
+	} else {
+		// 同一毫秒或时钟回拨时沿用上次的时间戳
+		g.counter++
+		if g.counter > 0xfff {
+			g.timestamp++
+			g.counter = 0
+		}
+	}
+	ts, counter := g.timestamp, g.counter
+	g.Unlock()
+
+	binary.BigEndian.PutUint16(u[4:6], uint16(ts))
+	binary.BigEndian.PutUint32(u[0:4], uint32(ts>>16))
+	binary.BigEndian.PutUint16(u[6:8], 0x7000|counter)
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return u, nil
+}
+
+func (g *UUIDv7Generator) Generate() (string, error) {
+	u, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// ULID is a universally unique lexicographically sortable identifier:
+// 48 bits of unix milliseconds followed by 80 random bits.
+type ULID [16]byte
+
+// String returns the 26 characters Crockford base32 form.
+func (u ULID) String() string {
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	var buf [26]byte
+	// 128 bits 按5位一组编码，最高位的一组只有3位
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// ULIDGenerator generates monotonic ULIDs: within the same millisecond the
+// random part of the previous ULID is incremented by one.
+type ULIDGenerator struct {
+	sync.Mutex
+	rand io.Reader
+	last ULID
+}
+
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{rand: rand.Reader}
+}
+
+func (g *ULIDGenerator) Next() (u ULID, err error) {
+	g.Lock()
+	defer g.Unlock()
+
+	now := uint64(time.Now().UnixMilli())
+	last := binary.BigEndian.Uint64(g.last[:8]) >> 16
+	if now > last {
+		if _, err = io.ReadFull(g.rand, u[6:]); err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(u[4:6], uint16(now))
+		binary.BigEndian.PutUint32(u[0:4], uint32(now>>16))
+	} else {
+		u = g.last
+		// 随机部分加一，溢出时返回错误
+		for i := len(u) - 1; ; i-- {
+			if i < 6 {
+				return ULID{}, ErrRandomOverflow
+			}
+			if u[i]++; u[i] != 0 {
+				break
+			}
+		}
+	}
+	g.last = u
+	return u, nil
+}
+
+func (g *ULIDGenerator) Generate() (string, error) {
+	u, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}